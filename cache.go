@@ -0,0 +1,22 @@
+package modfs
+
+import (
+	"io/fs"
+
+	"github.com/dolmen-go/modfs/cachefs"
+)
+
+// NewCachedProxy returns a [ModFS] that reads from the local directory
+// cacheDir, which has the layout of a proxy (like GOMODCACHE/cache/download),
+// and falls back to remote on cache miss. Files fetched from remote are
+// stored in cacheDir.
+//
+// Only immutable files (.info, .mod, .zip and .ziphash of versions) are
+// cached: @latest and @v/list are always fetched from remote.
+//
+// This is a shortcut for:
+//
+//	modfs.New(cachefs.Wrap(remote, cacheDir), opts...)
+func NewCachedProxy(cacheDir string, remote fs.FS, opts ...Option) *ModFS {
+	return New(cachefs.Wrap(remote, cacheDir), opts...)
+}
@@ -0,0 +1,87 @@
+package modfs_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestCachedProxy(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/cached", "v1.0.0", map[string]string{
+		"go.mod":    "module example.com/cached\n",
+		"cached.go": "package cached\n",
+	})
+	addModule(t, proxy, "example.com/cached", "v1.1.0", map[string]string{
+		"go.mod":    "module example.com/cached\n",
+		"cached.go": "package cached\n",
+	})
+	cacheDir := t.TempDir()
+
+	use := func(t *testing.T) []string {
+		t.Helper()
+		remote := &recordFS{FS: proxy}
+		ver := openVersion(t, modfs.NewCachedProxy(cacheDir, remote), "example.com/cached", "v1.0.0")
+		if _, err := ver.GoMod(); err != nil {
+			t.Fatal(err)
+		}
+		fsys, err := ver.OpenFS()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fsys.Close()
+		b, err := fsys.ReadFile("cached.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "package cached\n" {
+			t.Errorf("cached.go: got %q", b)
+		}
+		return remote.opened
+	}
+
+	t.Run("cold", func(t *testing.T) {
+		opened := use(t)
+		want := []string{
+			"example.com/cached/@latest",
+			"example.com/cached/@v/v1.0.0.info",
+			"example.com/cached/@v/v1.0.0.mod",
+			"example.com/cached/@v/v1.0.0.zip",
+		}
+		if !slices.Equal(opened, want) {
+			t.Errorf("remote: got %q, want %q", opened, want)
+		}
+		for _, name := range want[1:] {
+			b, err := os.ReadFile(filepath.Join(cacheDir, filepath.FromSlash(name)))
+			if err != nil {
+				t.Error(err)
+			} else if string(b) != string(proxy[name].Data) {
+				t.Errorf("%s: content mismatch", name)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(cacheDir, "example.com/cached/@latest")); err == nil {
+			t.Error("@latest cached")
+		}
+	})
+
+	t.Run("warm", func(t *testing.T) {
+		opened := use(t)
+		// Only the mutable @latest is fetched
+		if want := []string{"example.com/cached/@latest"}; !slices.Equal(opened, want) {
+			t.Errorf("remote: got %q, want %q", opened, want)
+		}
+	})
+
+	// No temporary file left behind
+	filepath.WalkDir(cacheDir, func(p string, d os.DirEntry, err error) error {
+		if strings.Contains(d.Name(), ".tmp") {
+			t.Errorf("temporary file left: %s", p)
+		}
+		return err
+	})
+}
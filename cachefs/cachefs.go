@@ -0,0 +1,186 @@
+// Package cachefs provides a disk cache for the files of a Go module proxy
+// exposed as an [io/fs.FS], such as an [github.com/dolmen-go/modfs/httpfs.HTTPFS].
+package cachefs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FS is a read-through cache of a module proxy. See [Wrap].
+type FS struct {
+	dir    string
+	remote fs.FS
+}
+
+// Wrap returns an [FS] that reads from the local directory dir, which has
+// the layout of a proxy (like GOMODCACHE/cache/download), and falls back to
+// remote on cache miss. Files fetched from remote are stored in dir:
+//
+//	modfs.New(cachefs.Wrap(hfs, dir))
+//
+// Only immutable files (.info, .mod, .zip and .ziphash of versions), which
+// never change once published by a proxy, are cached: mutable files
+// (@latest and @v/list) are always fetched from remote. Responses which
+// are obviously not the expected file (HTML pages, .zip files which are not
+// zip archives) are not cached.
+func Wrap(remote fs.FS, dir string) *FS {
+	return &FS{dir: dir, remote: remote}
+}
+
+// isImmutable reports whether the file name of a proxy never changes once
+// published.
+func isImmutable(name string) bool {
+	if path.Base(path.Dir(name)) != "@v" {
+		return false
+	}
+	switch path.Ext(name) {
+	case ".info", ".mod", ".zip", ".ziphash":
+		return true
+	}
+	return false
+}
+
+// Local returns the FS of the local directory, which never falls back to
+// remote. It is used by the Offline mode of [github.com/dolmen-go/modfs.ModFS].
+func (c *FS) Local() fs.FS {
+	return os.DirFS(c.dir)
+}
+
+// Open implements [fs.FS].
+func (c *FS) Open(name string) (fs.File, error) {
+	return c.OpenContext(context.Background(), name)
+}
+
+// OpenContext is like [FS.Open], but the download on cache miss is bound to
+// ctx. The context is passed to remote if it implements the OpenContext
+// method.
+func (c *FS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if !isImmutable(name) {
+		return c.openRemote(ctx, name)
+	}
+
+	localPath := filepath.Join(c.dir, filepath.FromSlash(name))
+	f, err := os.Open(localPath)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// Cache miss
+	rf, err := c.openRemote(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !isCacheable(name, rf) {
+		// Not stored: the checks of the caller apply to the response
+		return rf, nil
+	}
+	defer rf.Close()
+	var r io.Reader = &ctxReader{ctx: ctx, r: rf}
+	if path.Ext(name) == ".zip" {
+		if r, err = checkZip(r); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	if err := store(localPath, r); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return os.Open(localPath)
+}
+
+// isCacheable reports whether the content type of the file f of remote, if
+// reported (files with a ContentType() string method, such as from
+// [github.com/dolmen-go/modfs/httpfs.HTTPFS]), is expected for name. HTML
+// pages (error or login pages of a captive portal) are not cached, nor .zip
+// files of other types than application/zip and application/octet-stream.
+func isCacheable(name string, f fs.File) bool {
+	ct, ok := f.(interface{ ContentType() string })
+	if !ok || ct.ContentType() == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(ct.ContentType())
+	if err != nil {
+		return false
+	}
+	if path.Ext(name) == ".zip" {
+		return mediaType == "application/zip" || mediaType == "application/octet-stream"
+	}
+	return mediaType != "text/html"
+}
+
+// ErrNotZip is returned on cache miss when the .zip file fetched from remote
+// doesn't start with the signature of a zip archive. It is not cached.
+var ErrNotZip = errors.New("not a zip archive")
+
+// checkZip checks the signature at the start of r: either a local file
+// header, or the end of central directory record of an empty archive. The
+// returned reader yields the full content of r.
+func checkZip(r io.Reader) (io.Reader, error) {
+	var magic [4]byte
+	n, err := io.ReadFull(r, magic[:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	if m := string(magic[:n]); m != "PK\x03\x04" && m != "PK\x05\x06" {
+		return nil, ErrNotZip
+	}
+	return io.MultiReader(bytes.NewReader(magic[:]), r), nil
+}
+
+func (c *FS) openRemote(ctx context.Context, name string) (fs.File, error) {
+	if cfs, ok := c.remote.(interface {
+		OpenContext(context.Context, string) (fs.File, error)
+	}); ok {
+		return cfs.OpenContext(ctx, name)
+	}
+	return c.remote.Open(name)
+}
+
+// store writes the content of r to localPath, atomically.
+func store(localPath string, r io.Reader) error {
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(localPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), localPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// ctxReader is an [io.Reader] that stops reading when the context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,178 @@
+package cachefs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// countFS counts the opens of the files of an [fs.FS].
+type countFS struct {
+	fs.FS
+	opened []string
+}
+
+func (c *countFS) Open(name string) (fs.File, error) {
+	c.opened = append(c.opened, name)
+	return c.FS.Open(name)
+}
+
+func TestWrap(t *testing.T) {
+	remote := &countFS{FS: fstest.MapFS{
+		"example.com/m/@latest":       {Data: []byte(`{"Version":"v1.0.0"}`)},
+		"example.com/m/@v/list":       {Data: []byte("v1.0.0\n")},
+		"example.com/m/@v/v1.0.0.mod": {Data: []byte("module example.com/m\n")},
+	}}
+	dir := t.TempDir()
+	fsys := Wrap(remote, dir)
+
+	for range 2 {
+		for _, name := range []string{"example.com/m/@latest", "example.com/m/@v/list", "example.com/m/@v/v1.0.0.mod"} {
+			b, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := fs.ReadFile(remote.FS, name)
+			if string(b) != string(want) {
+				t.Errorf("%s: got %q, want %q", name, b, want)
+			}
+		}
+	}
+	// Mutable files are fetched each time
+	want := []string{
+		"example.com/m/@latest", "example.com/m/@v/list", "example.com/m/@v/v1.0.0.mod",
+		"example.com/m/@latest", "example.com/m/@v/list",
+	}
+	if !slices.Equal(remote.opened, want) {
+		t.Errorf("remote: got %q, want %q", remote.opened, want)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "example.com/m/@v/v1.0.0.mod")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "example.com/m/@latest")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("@latest stored in the cache: %v", err)
+	}
+
+	_, err := fsys.Open("example.com/m/@v/v2.0.0.mod")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := fsys.Open("../escape"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("invalid path: got %v, want fs.ErrInvalid", err)
+	}
+
+	// Local serves only the cached files
+	remote.opened = nil
+	if b, err := fs.ReadFile(fsys.Local(), "example.com/m/@v/v1.0.0.mod"); err != nil || string(b) != "module example.com/m\n" {
+		t.Errorf("Local: got %q, %v", b, err)
+	}
+	if _, err := fs.ReadFile(fsys.Local(), "example.com/m/@latest"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Local: @latest: got %v, want fs.ErrNotExist", err)
+	}
+	if len(remote.opened) > 0 {
+		t.Errorf("Local: remote opened: %q", remote.opened)
+	}
+}
+
+func TestOpenContextCanceled(t *testing.T) {
+	remote := fstest.MapFS{
+		"example.com/m/@v/v1.0.0.mod": {Data: []byte("module example.com/m\n")},
+	}
+	dir := t.TempDir()
+	fsys := Wrap(remote, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fsys.OpenContext(ctx, "example.com/m/@v/v1.0.0.mod"); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	// Nothing stored, not even a temporary file
+	entries, err := os.ReadDir(filepath.Join(dir, "example.com/m/@v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("cache not empty: %v", entries)
+	}
+}
+
+// typedFS reports the content type of its files, like HTTPFS.
+type typedFS struct {
+	fs.FS
+	contentType string
+}
+
+func (t *typedFS) Open(name string) (fs.File, error) {
+	f, err := t.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &typedFile{File: f, contentType: t.contentType}, nil
+}
+
+type typedFile struct {
+	fs.File
+	contentType string
+}
+
+func (f *typedFile) ContentType() string { return f.contentType }
+
+func TestNotCached(t *testing.T) {
+	const name = "example.com/m/@v/v1.0.0.zip"
+	html := []byte("<html><body>Login</body></html>")
+
+	for _, tt := range []struct {
+		contentType string
+		data        []byte
+		wantErr     error
+	}{
+		{"text/html; charset=utf-8", html, nil}, // Served as is
+		{"", html, ErrNotZip},
+		{"application/zip", []byte("PK"), ErrNotZip},
+	} {
+		t.Run(tt.contentType, func(t *testing.T) {
+			remote := &countFS{FS: &typedFS{
+				FS:          fstest.MapFS{name: {Data: tt.data}},
+				contentType: tt.contentType,
+			}}
+			dir := t.TempDir()
+			fsys := Wrap(remote, dir)
+
+			// The second read is not served from the cache
+			for range 2 {
+				b, err := fs.ReadFile(fsys, name)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				if err == nil && string(b) != string(tt.data) {
+					t.Errorf("got %q, want %q", b, tt.data)
+				}
+			}
+			if len(remote.opened) != 2 {
+				t.Errorf("remote opened %d times, want 2", len(remote.opened))
+			}
+			if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("stored in the cache: %v", err)
+			}
+		})
+	}
+
+	// A zip archive is cached
+	zip := []byte("PK\x05\x06" + string(make([]byte, 18)))
+	remote := &countFS{FS: &typedFS{FS: fstest.MapFS{name: {Data: zip}}, contentType: "application/zip"}}
+	fsys := Wrap(remote, t.TempDir())
+	for range 2 {
+		if b, err := fs.ReadFile(fsys, name); err != nil || string(b) != string(zip) {
+			t.Fatalf("got %q, %v", b, err)
+		}
+	}
+	if len(remote.opened) != 1 {
+		t.Errorf("remote opened %d times, want 1", len(remote.opened))
+	}
+}
@@ -0,0 +1,113 @@
+package modfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"golang.org/x/mod/module"
+)
+
+// DefaultProbeModule is the module used by [ModFS.Capabilities] to probe the proxy.
+const DefaultProbeModule = "golang.org/x/mod"
+
+// ProbeModule sets the module used by [ModFS.Capabilities] to probe the
+// proxy, instead of [DefaultProbeModule]. The module must be available
+// from the proxy.
+func ProbeModule(path string) Option {
+	return func(m *ModFS) {
+		m.probeModule = path
+	}
+}
+
+// Capabilities reports the optional features supported by a proxy.
+type Capabilities struct {
+	// Latest reports whether the $module/@latest endpoint is served.
+	Latest bool
+	// ZipHash reports whether $module/@v/$version.ziphash files are
+	// served, as in the module cache layout (GOMODCACHE/cache/download).
+	ZipHash bool
+	// SumDB reports whether the proxy supports proxying the
+	// sum.golang.org checksum database (sumdb/sum.golang.org/supported).
+	SumDB bool
+}
+
+// Capabilities probes the proxy for optional features, using the module
+// set by [ProbeModule] (or [DefaultProbeModule]).
+//
+// The result of the first successful probe is cached. Concurrent calls
+// before it completes probe the proxy each.
+//
+// The Disable-Module-Fetch header of the protocol can't be probed as
+// it is sent by the client.
+func (m *ModFS) Capabilities(ctx context.Context) (Capabilities, error) {
+	m.mu.Lock()
+	cached := m.caps
+	m.mu.Unlock()
+	if cached != nil {
+		return *cached, nil
+	}
+
+	// The lock is not held during the requests to the proxy
+	caps, err := m.probeCapabilities(ctx)
+	if err != nil {
+		return caps, err
+	}
+
+	m.mu.Lock()
+	m.caps = &caps
+	m.mu.Unlock()
+	return caps, nil
+}
+
+func (m *ModFS) probeCapabilities(ctx context.Context) (Capabilities, error) {
+	probeModule := m.probeModule
+	if probeModule == "" {
+		probeModule = DefaultProbeModule
+	}
+
+	escPath, err := escapePath(probeModule)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	mod := Module{fs: m, escPath: escPath, Path: probeModule}
+
+	var caps Capabilities
+	if caps.Latest, err = m.probe(ctx, mod.file("@latest")); err != nil {
+		return caps, err
+	}
+	if caps.SumDB, err = m.probe(ctx, "sumdb/sum.golang.org/supported"); err != nil {
+		return caps, err
+	}
+
+	versions, err := mod.listVersionsLimit(ctx, 1)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return caps, err
+	}
+	if len(versions) > 0 {
+		ver, err := module.EscapeVersion(versions[0].Version)
+		if err != nil {
+			return caps, err
+		}
+		if caps.ZipHash, err = m.probe(ctx, mod.file("@v/"+ver+".ziphash")); err != nil {
+			return caps, err
+		}
+	}
+	return caps, nil
+}
+
+// probe reports whether the file at path exists.
+func (m *ModFS) probe(ctx context.Context, path string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	f, err := m.open(ctx, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	f.Close()
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package modfs_test
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+// countFS counts the calls to Open.
+type countFS struct {
+	fs.FS
+	opens int
+}
+
+func (c *countFS) Open(name string) (fs.File, error) {
+	c.opens++
+	return c.FS.Open(name)
+}
+
+// ctxFS records the value of ctxKey in the context of the opening of the
+// @v/list files.
+type ctxFS struct {
+	fs.FS
+	listValue any
+}
+
+type ctxKey struct{}
+
+func (c *ctxFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	if strings.HasSuffix(name, "/@v/list") {
+		c.listValue = ctx.Value(ctxKey{})
+	}
+	return c.FS.Open(name)
+}
+
+func TestCapabilities(t *testing.T) {
+	const probe = "golang.org/x/mod"
+
+	full := fstest.MapFS{
+		"sumdb/sum.golang.org/supported": &fstest.MapFile{},
+	}
+	addModule(t, full, probe, "v0.1.0", map[string]string{"go.mod": "module " + probe + "\n"})
+	full[probe+"/@v/v0.1.0.ziphash"] = &fstest.MapFile{Data: []byte("h1:xxx")}
+
+	noLatest := fstest.MapFS{}
+	addModule(t, noLatest, "example.com/probe", "v1.0.0", nil)
+	delete(noLatest, "example.com/probe/@latest")
+
+	tests := []struct {
+		name  string
+		proxy fs.FS
+		opts  []modfs.Option
+		want  modfs.Capabilities
+	}{
+		{"full", full, nil, modfs.Capabilities{Latest: true, ZipHash: true, SumDB: true}},
+		{"empty", fstest.MapFS{}, nil, modfs.Capabilities{}},
+		{"no-latest", noLatest, []modfs.Option{modfs.ProbeModule("example.com/probe")}, modfs.Capabilities{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfs := &countFS{FS: tt.proxy}
+			m := modfs.New(cfs, tt.opts...)
+			caps, err := m.Capabilities(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if caps != tt.want {
+				t.Errorf("got %+v, want %+v", caps, tt.want)
+			}
+
+			// Cached
+			opens := cfs.opens
+			if _, err := m.Capabilities(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if cfs.opens != opens {
+				t.Error("result not cached")
+			}
+		})
+	}
+
+	t.Run("context", func(t *testing.T) {
+		cfs := &ctxFS{FS: full}
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "probe"))
+		defer cancel()
+		if _, err := modfs.New(cfs).Capabilities(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if cfs.listValue != "probe" {
+			t.Error("@v/list: context not passed")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := modfs.New(full).Capabilities(ctx); err != context.Canceled {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package modfs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/dolmen-go/modfs/cachefs"
+)
+
+// Client is a [ModFS] configured in one place: the backing FS (the base
+// of the proxy, such as an [github.com/dolmen-go/modfs/httpfs.HTTPFS] with
+// its timeouts and retries), the local cache ([CacheDir]), the verification
+// policy ([DefaultOpenOptions], [NoSumDB]) and the concurrency limits
+// ([MaxConcurrency]) are set by the options of [NewClient].
+//
+// All the methods of ModFS are available.
+type Client struct {
+	*ModFS
+}
+
+// NewClient returns a [Client] of the proxy fsys.
+//
+//	client := modfs.NewClient(hfs,
+//		modfs.CacheDir(cacheDir),
+//		modfs.MaxConcurrency(8),
+//		modfs.DefaultOpenOptions(modfs.VerifyContentType()),
+//	)
+//	ver, err := client.Version("golang.org/x/mod@latest")
+func NewClient(fsys fs.FS, opts ...Option) *Client {
+	return &Client{New(fsys, opts...)}
+}
+
+// Module opens the module path. See [ModFS.OpenModule].
+func (c *Client) Module(path string) (*Module, error) {
+	return c.OpenModule(path)
+}
+
+// Version returns the version of a module from a query "path@version",
+// as in "go get path@version". The version "latest" is resolved with the
+// @latest endpoint of the proxy (see [Module.VersionLatest]).
+func (c *Client) Version(query string) (*Version, error) {
+	path, version, ok := strings.Cut(query, "@")
+	if !ok || path == "" || version == "" {
+		return nil, fmt.Errorf("%q: path@version expected", query)
+	}
+	mod, err := c.OpenModule(path)
+	if err != nil {
+		return nil, err
+	}
+	if version == "latest" {
+		return mod.VersionLatest()
+	}
+	return mod.Version(version)
+}
+
+// CacheDir makes the [ModFS] read through the local cache dir: the
+// immutable files of the proxy are stored in dir. See [NewCachedProxy].
+func CacheDir(dir string) Option {
+	return func(m *ModFS) {
+		m.cacheDir = dir
+	}
+}
+
+// MaxConcurrency limits to n the number of files of the backing FS open
+// concurrently, such as HTTP requests and downloads from the proxy. A slot
+// is held from the opening of a file until it is closed: a [ZipFS] of a
+// backing FS providing random access ([io.ReaderAt]) holds its slot until
+// the ZipFS is closed, and opening more files than n blocks until one is
+// closed. With [CacheDir], only the files fetched on cache miss count.
+func MaxConcurrency(n int) Option {
+	return func(m *ModFS) {
+		m.maxConcurrency = n
+	}
+}
+
+// DefaultOpenOptions sets the options applied to every opening of a zip
+// archive: by [Version.OpenFS] and [Version.OpenFSStreaming] (before the
+// options of each call), and by the methods using the archive, such as
+// [Version.Extract], [Version.ListFiles], [Version.Files],
+// [Version.ModAndSum] and [Version.Hash]. This allows to set a verification
+// policy, such as [VerifyZipHash], for all the modules.
+func DefaultOpenOptions(opts ...OpenOption) Option {
+	return func(m *ModFS) {
+		m.openOpts = append(m.openOpts, opts...)
+	}
+}
+
+// limitFS limits the number of files of an FS open concurrently.
+type limitFS struct {
+	fs  fs.FS
+	sem chan struct{}
+}
+
+func newLimitFS(fsys fs.FS, n int) *limitFS {
+	return &limitFS{fs: fsys, sem: make(chan struct{}, n)}
+}
+
+// Open implements [fs.FS].
+func (l *limitFS) Open(name string) (fs.File, error) {
+	return l.OpenContext(context.Background(), name)
+}
+
+// OpenContext implements [ContextFS]: the context applies also to the wait
+// for a slot. The slot is released when the file is closed.
+func (l *limitFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ctx.Err()}
+	}
+	release := sync.OnceFunc(func() { <-l.sem })
+	var f fs.File
+	var err error
+	if cfs, ok := l.fs.(ContextFS); ok {
+		f, err = cfs.OpenContext(ctx, name)
+	} else {
+		f, err = l.fs.Open(name)
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return newScopedFile(f, release), nil
+}
+
+// wrapFS applies to the backing FS the options that wrap it.
+func (m *ModFS) wrapFS() {
+	if m.maxConcurrency > 0 {
+		m.fs = newLimitFS(m.fs, m.maxConcurrency)
+	}
+	if m.cacheDir != "" {
+		m.fs = cachefs.Wrap(m.fs, m.cacheDir)
+	}
+	if m.offline {
+		m.fs = newOfflineFS(m.fs)
+	}
+}
@@ -0,0 +1,141 @@
+package modfs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestClient(t *testing.T) {
+	proxy := fstest.MapFS{}
+	for _, v := range []string{"v1.0.0", "v1.1.0"} {
+		addModule(t, proxy, "example.com/c", v, map[string]string{"c.go": "package c\n"})
+	}
+	cacheDir := t.TempDir()
+	client := modfs.NewClient(proxy, modfs.CacheDir(cacheDir), modfs.DefaultOpenOptions(modfs.VerifyZipHash()))
+
+	ver, err := client.Version("example.com/c@latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ver.Version != "v1.1.0" {
+		t.Errorf("@latest: got %s", ver.Version)
+	}
+	if ver, err = client.Version("example.com/c@v1.0.0"); err != nil || ver.Version != "v1.0.0" {
+		t.Fatalf("@v1.0.0: got %v, %v", ver, err)
+	}
+	for _, query := range []string{"example.com/c", "example.com/c@", "@v1.0.0"} {
+		if _, err := client.Version(query); err == nil {
+			t.Errorf("%q: no error", query)
+		}
+	}
+	if mod, err := client.Module("example.com/c"); err != nil || mod.Path != "example.com/c" {
+		t.Errorf("Module: got %v, %v", mod, err)
+	}
+
+	// The default options apply: no .ziphash
+	if _, err := ver.OpenFS(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenFS: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := ver.OpenFSStreaming(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenFSStreaming: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := ver.ListFiles(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ListFiles: got %v, want fs.ErrNotExist", err)
+	}
+	if _, _, err := ver.ModAndSum(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ModAndSum: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := ver.Hash(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Hash: got %v, want fs.ErrNotExist", err)
+	}
+	// The cache is used
+	if _, err := ver.GoMod(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "example.com/c/@v/v1.0.0.mod")); err != nil {
+		t.Errorf("not cached: %v", err)
+	}
+}
+
+// slowFS tracks the number of files open concurrently.
+type slowFS struct {
+	fs.FS
+	current, max atomic.Int32
+}
+
+func (s *slowFS) Open(name string) (fs.File, error) {
+	n := s.current.Add(1)
+	for {
+		m := s.max.Load()
+		if n <= m || s.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	f, err := s.FS.Open(name)
+	if err != nil {
+		s.current.Add(-1)
+		return nil, err
+	}
+	return &slowFile{File: f, s: s}, nil
+}
+
+type slowFile struct {
+	fs.File
+	s *slowFS
+}
+
+func (f *slowFile) Close() error {
+	f.s.current.Add(-1)
+	return f.File.Close()
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/mc", "v1.0.0", nil)
+	remote := &slowFS{FS: proxy}
+	client := modfs.NewClient(remote, modfs.MaxConcurrency(2))
+	ver, err := client.Version("example.com/mc@v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Module("example.com/mc"); err != nil {
+				t.Error(err)
+			}
+		}()
+		// The slot is held until the download is closed
+		go func() {
+			defer wg.Done()
+			r, err := ver.Zip()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer r.Close()
+			time.Sleep(5 * time.Millisecond)
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if m := remote.max.Load(); m > 2 {
+		t.Errorf("%d files open concurrently, want at most 2", m)
+	}
+}
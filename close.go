@@ -0,0 +1,155 @@
+package modfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ErrClosed is returned by the operations of a [ModFS] started after
+// [ModFS.Close], and by the operations interrupted by Close.
+var ErrClosed = errors.New("closed ModFS")
+
+// Close cancels the in-flight operations of m and removes the temporary
+// files it created (the downloads of zip archives of [Version.OpenFS] and
+// [Version.OpenFSStreaming]): the FS opened from them become unusable.
+// Operations started after Close fail with [ErrClosed].
+//
+// Cancellation interrupts requests only if the backing FS implements
+// [ContextFS].
+//
+// Close is safe for concurrent use and may be called multiple times.
+func (m *ModFS) Close() error {
+	m.cancel(ErrClosed)
+
+	m.tmpMu.Lock()
+	tmp := m.tmp
+	m.tmp = nil
+	m.tmpMu.Unlock()
+
+	var errs []error
+	for f := range tmp {
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closedErr returns ErrClosed if m is closed.
+func (m *ModFS) closedErr() error {
+	if m.ctx.Err() != nil {
+		return ErrClosed
+	}
+	return nil
+}
+
+// createTemp creates a temporary file for a zip archive, which is removed by
+// Close if not removed before with removeTemp.
+func (m *ModFS) createTemp() (*os.File, error) {
+	m.tmpMu.Lock()
+	defer m.tmpMu.Unlock()
+	if m.tmp == nil {
+		return nil, ErrClosed
+	}
+	f, err := os.CreateTemp("", "modfs_*.zip")
+	if err != nil {
+		return nil, err
+	}
+	m.tmp[f] = struct{}{}
+	return f, nil
+}
+
+// removeTemp closes and removes a file created by createTemp. Files
+// already removed by Close are ignored.
+func (m *ModFS) removeTemp(f *os.File) error {
+	m.tmpMu.Lock()
+	_, ok := m.tmp[f]
+	delete(m.tmp, f)
+	m.tmpMu.Unlock()
+	if !ok {
+		return nil
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// scopedContext returns a context which is done when either ctx is done or
+// m is closed. release must be called when the context is not used anymore.
+func (m *ModFS) scopedContext(ctx context.Context) (scoped context.Context, release func()) {
+	if ctx.Done() == nil {
+		// Background context: m.ctx is enough
+		return m.ctx, func() {}
+	}
+	scoped, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(m.ctx, func() { cancel(ErrClosed) })
+	return scoped, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// scopedFile calls release on Close: it releases the context of a file of a
+// [ContextFS], or the slot of a file of a [limitFS].
+type scopedFile struct {
+	fs.File
+	release func()
+}
+
+func (f *scopedFile) Close() error {
+	f.release()
+	return f.File.Close()
+}
+
+// ContentType forwards the content type of the file, if available (see
+// [VerifyContentType]).
+func (f *scopedFile) ContentType() string {
+	if ct, ok := f.File.(interface{ ContentType() string }); ok {
+		return ct.ContentType()
+	}
+	return ""
+}
+
+func (f *scopedFile) unwrap() fs.File {
+	return f.File
+}
+
+// isLocalFile reports whether f, possibly wrapped by [ModFS.open], is a file
+// of the local file system.
+func isLocalFile(f fs.File) bool {
+	if sf, ok := f.(interface{ unwrap() fs.File }); ok {
+		f = sf.unwrap()
+	}
+	_, ok := f.(*os.File)
+	return ok
+}
+
+// newScopedFile wraps f to call release on Close, keeping the [io.ReaderAt]
+// and [io.Seeker] capabilities of f.
+func newScopedFile(f fs.File, release func()) fs.File {
+	sf := &scopedFile{File: f, release: release}
+	ra, isReaderAt := f.(io.ReaderAt)
+	sk, isSeeker := f.(io.Seeker)
+	switch {
+	case isReaderAt && isSeeker:
+		return &struct {
+			*scopedFile
+			io.ReaderAt
+			io.Seeker
+		}{sf, ra, sk}
+	case isReaderAt:
+		return &struct {
+			*scopedFile
+			io.ReaderAt
+		}{sf, ra}
+	case isSeeker:
+		return &struct {
+			*scopedFile
+			io.Seeker
+		}{sf, sk}
+	}
+	return sf
+}
@@ -0,0 +1,95 @@
+package modfs_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/httpfs"
+)
+
+func TestClose(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/close/@latest":
+			w.Write([]byte(`{"Version":"v1.0.0","Time":"2025-01-01T00:00:00Z"}`))
+		case "/example.com/close/@v/v1.0.0.zip":
+			// Never ending download
+			w.Write([]byte(strings.Repeat("PK", 1000)))
+			w.(http.Flusher).Flush()
+			close(started)
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	hfs, err := httpfs.NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := modfs.New(hfs)
+	ver := openVersion(t, m, "example.com/close", "v1.0.0")
+
+	done := make(chan error)
+	go func() {
+		_, err := ver.OpenFS()
+		done <- err
+	}()
+	<-started
+	// Wait for the temporary file to be created
+	for range 100 {
+		if entries, _ := os.ReadDir(tmpDir); len(entries) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Close concurrently, multiple times
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("OpenFS: no error")
+		}
+		t.Log(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("download not cancelled")
+	}
+
+	if entries, _ := os.ReadDir(tmpDir); len(entries) > 0 {
+		t.Errorf("temporary files not removed: %v", entries)
+	}
+
+	if _, err := m.OpenModule("example.com/close"); !errors.Is(err, modfs.ErrClosed) {
+		t.Errorf("OpenModule: got %v, want ErrClosed", err)
+	}
+	if _, err := ver.GoMod(); !errors.Is(err, modfs.ErrClosed) {
+		t.Errorf("GoMod: got %v, want ErrClosed", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
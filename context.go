@@ -0,0 +1,84 @@
+package modfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+)
+
+// ContextFS is an [fs.FS] that allows to cancel the opening of files with a
+// [context.Context]. The context applies also to reading the opened file.
+//
+// If the FS given to [New] implements ContextFS, the *Context methods
+// ([ModFS.OpenModuleContext], [Version.OpenFSContext]...) use it to cancel
+// requests. [github.com/dolmen-go/modfs/httpfs.HTTPFS] implements it.
+type ContextFS interface {
+	fs.FS
+	OpenContext(ctx context.Context, name string) (fs.File, error)
+}
+
+// open opens the file name of the backing FS.
+//
+// With a [ContextFS], the file is also bound to the lifetime of m (see
+// [ModFS.Close]).
+func (m *ModFS) open(ctx context.Context, name string) (fs.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if err := m.closedErr(); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	cfs, ok := m.fs.(ContextFS)
+	if !ok {
+		return m.fs.Open(name)
+	}
+	scoped, release := m.scopedContext(ctx)
+	f, err := cfs.OpenContext(scoped, name)
+	if err != nil {
+		release()
+		if m.closedErr() != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: ErrClosed}
+		}
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return f, nil // Bound to m.ctx only: nothing to release
+	}
+	return newScopedFile(f, release), nil
+}
+
+// readFile reads the file name of the backing FS.
+func (m *ModFS) readFile(ctx context.Context, name string) ([]byte, error) {
+	if _, ok := m.fs.(ContextFS); !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		if err := m.closedErr(); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return fs.ReadFile(m.fs, name)
+	}
+	f, err := m.open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// ctxReader is an [io.Reader] that stops reading when the context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,89 @@
+package modfs_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/httpfs"
+)
+
+func TestContextCanceled(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/ctx", "v0.9.0", nil)
+	addModule(t, proxy, "example.com/ctx", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/ctx\n",
+	})
+	m := modfs.New(proxy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.OpenModuleContext(ctx, "example.com/ctx"); !errors.Is(err, context.Canceled) {
+		t.Errorf("OpenModuleContext: got %v, want context.Canceled", err)
+	}
+
+	mod, err := m.OpenModule("example.com/ctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mod.ListVersionsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListVersionsContext: got %v, want context.Canceled", err)
+	}
+	if _, err := mod.VersionContext(ctx, "v0.9.0"); !errors.Is(err, context.Canceled) {
+		t.Errorf("VersionContext: got %v, want context.Canceled", err)
+	}
+	ver, err := mod.Version("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ver.GoModContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GoModContext: got %v, want context.Canceled", err)
+	}
+	if _, err := ver.OpenFSContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("OpenFSContext: got %v, want context.Canceled", err)
+	}
+}
+
+func TestOpenFSContextHTTP(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/ctx", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/ctx\n",
+	})
+
+	// The zip download stalls after the first bytes
+	started := make(chan struct{})
+	proxyHandler := http.FileServerFS(proxy)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ".zip") {
+			proxyHandler.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", "1000000")
+		w.Write([]byte("PK"))
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	hfs, err := httpfs.NewHTTPFS(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver := openVersion(t, modfs.New(hfs), "example.com/ctx", "v1.0.0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if _, err := ver.OpenFSContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,110 @@
+package modfs
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// version returns the version v of the module, without fetching its .info:
+// only the Version field of VersionInfo is set.
+func (m *Module) version(v string) (*Version, error) {
+	escVersion, err := module.EscapeVersion(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Path, err)
+	}
+	return &Version{
+		module:      m,
+		escVersion:  escVersion,
+		VersionInfo: VersionInfo{Version: v},
+	}, nil
+}
+
+// Deps returns the requirements listed in the go.mod of the given version
+// of the module (direct and indirect), in the order of go.mod.
+//
+// Only the .mod file of the version is fetched. As the go command does for
+// dependencies, directives unknown to [golang.org/x/mod/modfile] are ignored.
+func (m *Module) Deps(version string) ([]module.Version, error) {
+	ver, err := m.version(version)
+	if err != nil {
+		return nil, err
+	}
+	return ver.deps()
+}
+
+func (ver *Version) deps() ([]module.Version, error) {
+	f, err := ver.parseGoMod()
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]module.Version, len(f.Require))
+	for i, r := range f.Require {
+		deps[i] = r.Mod
+	}
+	return deps, nil
+}
+
+// WalkDeps resolves the dependency graph of root by fetching the go.mod of
+// each required module version from the proxy, then calls fn for each module
+// of the build list, sorted by path. Each module version is fetched once.
+//
+// The version of each module is the highest version required in the graph,
+// as in Minimal Version Selection. The graph is not pruned (as the go
+// command does for modules at go 1.17 or higher), so the versions selected
+// may be higher than those of "go list -m all". root itself is not reported.
+//
+// The replace and exclude directives are ignored, as a proxy can't honor
+// them.
+//
+// If fn returns an error, WalkDeps stops and returns that error.
+func WalkDeps(root *Version, fn func(module.Version) error) error {
+	m := root.module.fs
+	modules := map[string]*Module{root.module.Path: root.module}
+	selected := make(map[string]string)
+	visited := make(map[module.Version]bool)
+
+	queue := []*Version{root}
+	for len(queue) > 0 {
+		ver := queue[0]
+		queue = queue[1:]
+		deps, err := ver.deps()
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if dep.Path == root.module.Path || visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if cur, ok := selected[dep.Path]; !ok || semver.Compare(dep.Version, cur) > 0 {
+				selected[dep.Path] = dep.Version
+			}
+
+			mod := modules[dep.Path]
+			if mod == nil {
+				escPath, err := escapePath(dep.Path)
+				if err != nil {
+					return fmt.Errorf("%s@%s: %w", ver.module.Path, ver.Version, err)
+				}
+				mod = &Module{fs: m, escPath: escPath, Path: dep.Path}
+				modules[dep.Path] = mod
+			}
+			depVer, err := mod.version(dep.Version)
+			if err != nil {
+				return err
+			}
+			queue = append(queue, depVer)
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(selected)) {
+		if err := fn(module.Version{Path: path, Version: selected[path]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
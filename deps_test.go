@@ -0,0 +1,77 @@
+package modfs_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/mod/module"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestDeps(t *testing.T) {
+	proxy := fstest.MapFS{}
+	for _, m := range []struct{ path, version, gomod string }{
+		{"example.com/root", "v1.0.0", "module example.com/root\n\nrequire (\n\texample.com/a v1.0.0\n\texample.com/b v1.0.0\n)\n\nreplace example.com/a => ../a\n"},
+		{"example.com/a", "v1.0.0", "module example.com/a\n\nrequire example.com/c v1.1.0\n"},
+		{"example.com/b", "v1.0.0", "module example.com/b\n\nrequire (\n\texample.com/c v1.2.0\n\texample.com/root v0.9.0\n)\n"},
+		// Unknown directives are ignored, as by the go command
+		{"example.com/c", "v1.1.0", "module example.com/c\n\nfuturedirective example.com/d\n"},
+		// Requires a version of b higher than the one of root
+		{"example.com/c", "v1.2.0", "module example.com/c\n\nrequire example.com/b v1.1.0\n"},
+		{"example.com/b", "v1.1.0", "module example.com/b\n"},
+	} {
+		addModule(t, proxy, m.path, m.version, map[string]string{"go.mod": m.gomod})
+	}
+	remote := &recordFS{FS: proxy}
+	m := modfs.New(remote)
+
+	mod, err := m.OpenModule("example.com/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps, err := mod.Deps("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []module.Version{{Path: "example.com/c", Version: "v1.2.0"}, {Path: "example.com/root", Version: "v0.9.0"}}
+	if !slices.Equal(deps, want) {
+		t.Errorf("Deps: got %v, want %v", deps, want)
+	}
+
+	root := openVersion(t, m, "example.com/root", "v1.0.0")
+	remote.opened = nil
+	var list []module.Version
+	err = modfs.WalkDeps(root, func(mv module.Version) error {
+		list = append(list, mv)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []module.Version{
+		{Path: "example.com/a", Version: "v1.0.0"},
+		{Path: "example.com/b", Version: "v1.1.0"},
+		{Path: "example.com/c", Version: "v1.2.0"},
+	}
+	if !slices.Equal(list, want) {
+		t.Errorf("WalkDeps: got %v, want %v", list, want)
+	}
+	// Each go.mod is fetched once
+	slices.Sort(remote.opened)
+	if len(slices.Compact(slices.Clone(remote.opened))) != len(remote.opened) {
+		t.Errorf("files fetched multiple times: %q", remote.opened)
+	}
+
+	errStop := errors.New("stop")
+	n := 0
+	err = modfs.WalkDeps(root, func(module.Version) error {
+		n++
+		return errStop
+	})
+	if err != errStop || n != 1 {
+		t.Errorf("WalkDeps stopped: got %v after %d calls", err, n)
+	}
+}
@@ -0,0 +1,120 @@
+package modfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	modzip "golang.org/x/mod/zip"
+)
+
+// ErrTooLarge is returned by [Version.Extract] when the content of the
+// module exceeds [golang.org/x/mod/zip.MaxZipFile], the limit enforced by the go command.
+var ErrTooLarge = errors.New("module too large")
+
+// Extract writes the files of the module to the local directory destDir,
+// created if necessary, with their paths relative to the root of the module
+// (without the module@version/ prefix of the zip archive). The modification
+// times of the files are preserved. The archive is opened with
+// [Version.OpenFS] and opts.
+//
+// Paths which are not local (see [filepath.Localize]) are rejected, existing
+// files are never overwritten, and all the files and directories are
+// created through an [os.Root] of destDir, so symbolic links already present
+// in destDir can't make Extract write outside of it. The total size of the
+// files is limited to [golang.org/x/mod/zip.MaxZipFile] (500 MiB): beyond,
+// Extract fails with [ErrTooLarge].
+//
+// On error, the files already extracted are left in destDir.
+func (ver *Version) Extract(destDir string, opts ...OpenOption) error {
+	zfs, err := ver.OpenFS(opts...)
+	if err != nil {
+		return err
+	}
+	defer zfs.Close()
+
+	if err := os.MkdirAll(destDir, 0o777); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(destDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	remaining := int64(modzip.MaxZipFile)
+	err = fs.WalkDir(zfs, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		local, err := filepath.Localize(name)
+		if err != nil {
+			return &fs.PathError{Op: "extract", Path: name, Err: err}
+		}
+		if d.IsDir() {
+			// WalkDir visits the parent directories first
+			return mkdir(root, local)
+		}
+		if !d.Type().IsRegular() {
+			return &fs.PathError{Op: "extract", Path: name, Err: ErrIrregularFile}
+		}
+		n, err := extractFile(zfs, name, root, local, remaining)
+		remaining -= n
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s@%s: %w", ver.module.Path, ver.Version, err)
+	}
+	return nil
+}
+
+// mkdir creates the directory name in root, if it doesn't already exist.
+func mkdir(root *os.Root, name string) error {
+	err := root.Mkdir(name, 0o777)
+	if errors.Is(err, fs.ErrExist) {
+		if fi, errStat := root.Stat(name); errStat == nil && fi.IsDir() {
+			return nil
+		}
+	}
+	return err
+}
+
+// extractFile copies the file name of fsys to the new file dest of root. At
+// most limit bytes are written.
+func extractFile(fsys fs.FS, name string, root *os.Root, dest string, limit int64) (int64, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// O_EXCL doesn't follow symbolic links
+	out, err := root.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, io.LimitReader(f, limit+1))
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil && n > limit {
+		err = &fs.PathError{Op: "extract", Path: name, Err: ErrTooLarge}
+	}
+	if err == nil {
+		// os.Root has no Chtimes in Go 1.24: out.Name() is the path of the
+		// file just created, below destDir
+		err = os.Chtimes(out.Name(), time.Time{}, fi.ModTime())
+	}
+	return n, err
+}
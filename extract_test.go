@@ -0,0 +1,91 @@
+package modfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestExtract(t *testing.T) {
+	files := map[string]string{
+		"go.mod":         "module example.com/x\n",
+		"x.go":           "package x\n",
+		"sub/sub.go":     "package sub\n",
+		"sub/deep/d.txt": "deep\n",
+	}
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/x", "v1.0.0", files)
+	ver := openVersion(t, modfs.New(proxy), "example.com/x", "v1.0.0")
+
+	zfs, err := ver.OpenFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zfs.Close()
+
+	dest := filepath.Join(t.TempDir(), "x")
+	if err := ver.Extract(dest); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dest, filepath.FromSlash(name))
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: got %q, want %q", name, b, content)
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := fs.Stat(zfs, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.ModTime().Equal(want.ModTime()) {
+			t.Errorf("%s: got ModTime %v, want %v", name, fi.ModTime(), want.ModTime())
+		}
+	}
+
+	// Existing files are not overwritten
+	if err := ver.Extract(dest); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("second Extract: got %v, want fs.ErrExist", err)
+	}
+}
+
+func TestExtractSymlink(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/x", "v1.0.0", map[string]string{
+		"go.mod":     "module example.com/x\n",
+		"sub/sub.go": "package sub\n",
+	})
+	ver := openVersion(t, modfs.New(proxy), "example.com/x", "v1.0.0")
+
+	// A symbolic link of destDir points outside
+	tmp := t.TempDir()
+	outside := filepath.Join(tmp, "outside")
+	if err := os.Mkdir(outside, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(tmp, "x")
+	if err := os.Mkdir(dest, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "sub")); err != nil {
+		t.Skip(err)
+	}
+
+	if err := ver.Extract(dest); err == nil {
+		t.Error("Extract: no error")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "sub.go")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("sub.go written outside: %v", err)
+	}
+}
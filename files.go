@@ -0,0 +1,111 @@
+package modfs
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// ListFiles returns the sorted paths of the files of the module.
+//
+// Only the central directory of the zip archive is read: if the backing FS
+// provides random access ([io.ReaderAt]), the content of the files is not
+// fetched. Otherwise the whole archive has to be downloaded.
+func (ver *Version) ListFiles() ([]string, error) {
+	zr, closer, err := ver.openZip(context.Background(), ver.module.fs.newOpenOptions(nil))
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	files := ver.zipFiles(zr)
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+type zipFile struct {
+	name string // path in the module
+	*zip.File
+}
+
+// zipFiles returns the regular files of the zip archive of the module,
+// sorted by path.
+func (ver *Version) zipFiles(zr *zip.Reader) []zipFile {
+	prefix := ver.module.Path + "@" + ver.Version + "/"
+	files := make([]zipFile, 0, len(zr.File))
+	for _, f := range zr.File {
+		if name, ok := strings.CutPrefix(f.Name, prefix); ok && name != "" && !strings.HasSuffix(name, "/") {
+			files = append(files, zipFile{name, f})
+		}
+	}
+	slices.SortFunc(files, func(a, b zipFile) int { return strings.Compare(a.name, b.name) })
+	return files
+}
+
+// Files returns an iterator over the files of the module, in path order,
+// yielding the path and the content of each file. The content of a file is
+// read lazily and closed when the loop advances.
+//
+// The zip archive is opened when the iteration starts (downloaded if the
+// backing FS doesn't provide random access) and closed when it ends, even on
+// early break. If the archive can't be opened, a single file with an empty
+// path is yielded, whose reader fails with the error.
+func (ver *Version) Files() (iter.Seq2[string, io.ReadCloser], error) {
+	o := ver.module.fs.newOpenOptions(nil)
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	return func(yield func(string, io.ReadCloser) bool) {
+		zr, closer, err := ver.openZip(context.Background(), o)
+		if err != nil {
+			yield("", &lazyFile{err: err})
+			return
+		}
+		defer closer.Close()
+
+		for _, f := range ver.zipFiles(zr) {
+			r := &lazyFile{f: f.File}
+			more := yield(f.name, r)
+			r.Close()
+			if !more {
+				return
+			}
+		}
+	}, nil
+}
+
+// lazyFile opens a zip entry on first read. If err is set, reads fail
+// with it.
+type lazyFile struct {
+	f   *zip.File
+	r   io.ReadCloser
+	err error
+}
+
+func (l *lazyFile) Read(p []byte) (int, error) {
+	if l.r == nil && l.err == nil {
+		l.r, l.err = l.f.Open()
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.r.Read(p)
+}
+
+func (l *lazyFile) Close() error {
+	if l.r == nil {
+		l.err = fs.ErrClosed
+		return nil
+	}
+	err := l.r.Close()
+	l.r, l.err = nil, fs.ErrClosed
+	return err
+}
@@ -0,0 +1,222 @@
+package modfs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+// readAtFS counts the bytes read via io.ReaderAt from the files of an FS.
+type readAtFS struct {
+	fs.FS
+	read atomic.Int64
+}
+
+func (r *readAtFS) Open(name string) (fs.File, error) {
+	f, err := r.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ra, ok := f.(fileReaderAt)
+	if !ok {
+		return f, nil
+	}
+	return &readAtFile{ra, &r.read}, nil
+}
+
+type fileReaderAt interface {
+	fs.File
+	io.ReaderAt
+}
+
+type readAtFile struct {
+	fileReaderAt
+	read *atomic.Int64
+}
+
+func (f *readAtFile) ReadAt(b []byte, off int64) (int, error) {
+	n, err := f.fileReaderAt.ReadAt(b, off)
+	f.read.Add(int64(n))
+	return n, err
+}
+
+func TestListFiles(t *testing.T) {
+	// Incompressible content
+	big := make([]byte, 1<<20)
+	rnd := rand.NewChaCha8([32]byte{})
+	rnd.Read(big)
+
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/big", "v1.0.0", map[string]string{
+		"go.mod":       "module example.com/big\n",
+		"data/big.bin": string(big),
+		"big.go":       "package big\n",
+	})
+	zipSize := len(proxy["example.com/big/@v/v1.0.0.zip"].Data)
+
+	rfs := &readAtFS{FS: proxy}
+	ver := openVersion(t, modfs.New(rfs), "example.com/big", "v1.0.0")
+
+	files, err := ver.ListFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"big.go", "data/big.bin", "go.mod"}; !slices.Equal(files, want) {
+		t.Errorf("got %q, want %q", files, want)
+	}
+	read := rfs.read.Load()
+	t.Logf("%d bytes read of %d", read, zipSize)
+	if read == 0 || read > int64(zipSize)/10 {
+		t.Errorf("%d bytes read of %d: expected only the central directory", read, zipSize)
+	}
+
+	// Fallback: download
+	ver = openVersion(t, modfs.New(&streamFS{FS: proxy}), "example.com/big", "v1.0.0")
+	files, err = ver.ListFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("got %q", files)
+	}
+}
+
+func TestModAndSum(t *testing.T) {
+	const goMod = "module example.com/sum\n\nrequire example.com/dep v1.0.0\n"
+	const goSum = "example.com/dep v1.0.0 h1:xxx=\nexample.com/dep v1.0.0/go.mod h1:yyy=\n"
+
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/sum", "v1.0.0", map[string]string{
+		"go.mod": goMod,
+		"go.sum": goSum,
+		"sum.go": "package sum\n",
+	})
+	addModule(t, proxy, "example.com/nosum", "v1.0.0", map[string]string{
+		"go.mod":   "module example.com/nosum\n",
+		"nosum.go": "package nosum\n",
+	})
+
+	ver := openVersion(t, modfs.New(proxy), "example.com/sum", "v1.0.0")
+	gomod, gosum, err := ver.ModAndSum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gomod) != goMod {
+		t.Errorf("go.mod: got %q", gomod)
+	}
+	if string(gosum) != goSum {
+		t.Errorf("go.sum: got %q", gosum)
+	}
+
+	ver = openVersion(t, modfs.New(proxy), "example.com/nosum", "v1.0.0")
+	gomod, gosum, err = ver.ModAndSum()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gomod) != "module example.com/nosum\n" {
+		t.Errorf("go.mod: got %q", gomod)
+	}
+	if gosum != nil {
+		t.Errorf("go.sum: got %q, want nil", gosum)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	files := map[string]string{
+		"go.mod":         "module example.com/files\n",
+		"files.go":       "package files\n",
+		"sub/sub.go":     "package sub\n",
+		"sub/data.txt":   "some data",
+		"testdata/x.txt": "x",
+	}
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/files", "v1.0.0", files)
+
+	for name, fsys := range map[string]fs.FS{
+		"ReaderAt": proxy,
+		"stream":   &streamFS{FS: proxy},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ver := openVersion(t, modfs.New(fsys), "example.com/files", "v1.0.0")
+			seq, err := ver.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			var total, want int64
+			for name, r := range seq {
+				names = append(names, name)
+				n, err := io.Copy(io.Discard, r)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				total += n
+				want += int64(len(files[name]))
+			}
+			if want := []string{"files.go", "go.mod", "sub/data.txt", "sub/sub.go", "testdata/x.txt"}; !slices.Equal(names, want) {
+				t.Errorf("got %q, want %q", names, want)
+			}
+			if total != want {
+				t.Errorf("got %d bytes, want %d", total, want)
+			}
+
+			// Early break
+			seq, err = ver.Files()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kept io.Reader
+			for name, r := range seq {
+				if name != "files.go" {
+					t.Errorf("got %q, want files.go", name)
+				}
+				kept = r
+				break
+			}
+			if _, err := kept.Read(make([]byte, 1)); err == nil {
+				t.Error("reader still open after the loop")
+			}
+
+			// The archive is opened again by each loop
+			n := 0
+			for range seq {
+				n++
+			}
+			if n != len(files) {
+				t.Errorf("second loop: got %d files, want %d", n, len(files))
+			}
+		})
+	}
+}
+
+func TestFilesOpenError(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/files", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/files\n",
+	})
+	ver := openVersion(t, modfs.New(proxy), "example.com/files", "v1.0.0")
+	delete(proxy, "example.com/files/@v/v1.0.0.zip")
+
+	// The archive is not opened by Files, but by the loop
+	seq, err := ver.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name, r := range seq {
+		names = append(names, name)
+		if _, err := r.Read(make([]byte, 1)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("got %v, want fs.ErrNotExist", err)
+		}
+	}
+	if !slices.Equal(names, []string{""}) {
+		t.Errorf("got %q, want a single empty path", names)
+	}
+}
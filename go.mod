@@ -1,3 +1,5 @@
 module github.com/dolmen-go/modfs
 
 go 1.24.0
+
+require golang.org/x/mod v0.28.0
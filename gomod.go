@@ -0,0 +1,78 @@
+package modfs
+
+import (
+	"golang.org/x/mod/modfile"
+)
+
+// parseGoMod parses the go.mod of ver in lax mode (unknown directives are
+// ignored, like for dependencies in the go command).
+func (ver *Version) parseGoMod() (*modfile.File, error) {
+	data, err := ver.GoMod()
+	if err != nil {
+		return nil, err
+	}
+	// The file name in errors identifies the module and version
+	return modfile.ParseLax(ver.module.Path+"@"+ver.Version+"/go.mod", data, nil)
+}
+
+// ParseMod parses the go.mod of the version, for example to walk the
+// dependency graph. The module path, the go directive, and the require,
+// exclude, replace and retract directives are available in the result.
+//
+// Unlike the go command for dependencies, directives unknown to
+// [golang.org/x/mod/modfile] are reported as errors. Errors are prefixed with
+// "path@version/go.mod".
+//
+// The result is cached on ver: it must not be modified.
+func (ver *Version) ParseMod() (*modfile.File, error) {
+	ver.modMu.Lock()
+	defer ver.modMu.Unlock()
+	if ver.modFile != nil {
+		return ver.modFile, nil
+	}
+	data, err := ver.GoMod()
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.Parse(ver.module.Path+"@"+ver.Version+"/go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+	ver.modFile = f
+	return f, nil
+}
+
+// ValidateGoMod checks the syntax of the go.mod of the version.
+// Errors are prefixed with "path@version/go.mod".
+func (ver *Version) ValidateGoMod() error {
+	_, err := ver.parseGoMod()
+	return err
+}
+
+// goDirective returns the go directive of the go.mod of ver, or "" if
+// go.mod has no go directive.
+func (ver *Version) goDirective() (string, error) {
+	f, err := ver.parseGoMod()
+	if err != nil {
+		return "", err
+	}
+	if f.Go == nil {
+		return "", nil
+	}
+	return f.Go.Version, nil
+}
+
+// GoDirectiveDiff compares the go directive of the go.mod of a and b, for
+// example to report that an upgrade of a dependency from a to b bumps the
+// required Go version.
+//
+// Only the .mod files are fetched. A missing go directive is reported as "".
+func GoDirectiveDiff(a, b *Version) (oldVer, newVer string, changed bool, err error) {
+	if oldVer, err = a.goDirective(); err != nil {
+		return "", "", false, err
+	}
+	if newVer, err = b.goDirective(); err != nil {
+		return "", "", false, err
+	}
+	return oldVer, newVer, oldVer != newVer, nil
+}
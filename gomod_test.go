@@ -0,0 +1,190 @@
+package modfs_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestGoDirectiveDiff(t *testing.T) {
+	proxy := fstest.MapFS{}
+	for version, gomod := range map[string]string{
+		"v1.0.0": "module example.com/dep\n",
+		"v1.1.0": "module example.com/dep\n\ngo 1.21\n",
+		"v1.2.0": "module example.com/dep\n\ngo 1.21\n\nrequire example.com/other v1.0.0\n",
+		"v1.3.0": "module example.com/dep\n\ngo 1.23.0\n\ntoolchain go1.23.4\n",
+	} {
+		addModule(t, proxy, "example.com/dep", version, map[string]string{"go.mod": gomod})
+	}
+	m := modfs.New(proxy)
+
+	for _, tc := range []struct {
+		a, b           string
+		oldVer, newVer string
+		changed        bool
+	}{
+		{"v1.0.0", "v1.0.0", "", "", false},
+		{"v1.0.0", "v1.1.0", "", "1.21", true},
+		{"v1.1.0", "v1.2.0", "1.21", "1.21", false},
+		{"v1.2.0", "v1.3.0", "1.21", "1.23.0", true},
+		{"v1.3.0", "v1.0.0", "1.23.0", "", true},
+	} {
+		t.Run(tc.a+"-"+tc.b, func(t *testing.T) {
+			a := openVersion(t, m, "example.com/dep", tc.a)
+			b := openVersion(t, m, "example.com/dep", tc.b)
+			oldVer, newVer, changed, err := modfs.GoDirectiveDiff(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if oldVer != tc.oldVer || newVer != tc.newVer || changed != tc.changed {
+				t.Errorf("got (%q, %q, %t), want (%q, %q, %t)", oldVer, newVer, changed, tc.oldVer, tc.newVer, tc.changed)
+			}
+		})
+	}
+
+}
+
+func TestValidateGoMod(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/gomod", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/gomod\n\ngo 1.21\n\nrequire example.com/dep v1.0.0\n",
+	})
+	addModule(t, proxy, "example.com/gomod", "v1.1.0", map[string]string{
+		"go.mod": "module example.com/gomod\n\nrequire (\n\texample.com/dep v1.0.0\n",
+	})
+	m := modfs.New(proxy)
+
+	if err := openVersion(t, m, "example.com/gomod", "v1.0.0").ValidateGoMod(); err != nil {
+		t.Errorf("v1.0.0: %v", err)
+	}
+
+	err := openVersion(t, m, "example.com/gomod", "v1.1.0").ValidateGoMod()
+	if err == nil {
+		t.Fatal("v1.1.0: no error")
+	}
+	t.Log(err)
+	if !strings.Contains(err.Error(), "example.com/gomod@v1.1.0") {
+		t.Errorf("v1.1.0: module and version missing in error %q", err)
+	}
+}
+
+func TestGoModNormalizeLineEndings(t *testing.T) {
+	const crlf = "module example.com/crlf\r\n\r\ngo 1.21\r\n"
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/crlf", "v1.0.0", map[string]string{"go.mod": crlf})
+	ver := openVersion(t, modfs.New(proxy), "example.com/crlf", "v1.0.0")
+
+	b, err := ver.GoMod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != crlf {
+		t.Errorf("verbatim: got %q", b)
+	}
+
+	b, err = ver.GoMod(modfs.NormalizeLineEndings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "module example.com/crlf\n\ngo 1.21\n"; string(b) != want {
+		t.Errorf("normalized: got %q, want %q", b, want)
+	}
+}
+
+func TestOpenMod(t *testing.T) {
+	const gomod = "module example.com/openmod\n\ngo 1.21\n"
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/openmod", "v1.0.0-Beta", map[string]string{"go.mod": gomod})
+	// Files of the version must be escaped in the proxy
+	for name, f := range proxy {
+		if escaped := strings.Replace(name, "@v/v1.0.0-Beta.", "@v/v1.0.0-!beta.", 1); escaped != name {
+			delete(proxy, name)
+			proxy[escaped] = f
+		}
+	}
+	ver := openVersion(t, modfs.New(proxy), "example.com/openmod", "v1.0.0-Beta")
+
+	f, err := ver.OpenMod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(len(gomod)) {
+		t.Errorf("Size: got %d, want %d", fi.Size(), len(gomod))
+	}
+	b, err := io.ReadAll(f)
+	if err != nil || string(b) != gomod {
+		t.Errorf("got %q, %v", b, err)
+	}
+
+	delete(proxy, "example.com/openmod/@v/v1.0.0-!beta.mod")
+	if _, err := ver.OpenMod(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing .mod: got %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestParseMod(t *testing.T) {
+	const gomod = `module example.com/walk
+
+go 1.22
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.2.0 // indirect
+)
+
+exclude example.com/a v0.9.0
+`
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/walk", "v1.0.0", map[string]string{"go.mod": gomod})
+	addModule(t, proxy, "example.com/walk", "v1.1.0", map[string]string{"go.mod": "module example.com/walk\n\nunknown directive\n"})
+	remote := &recordFS{FS: proxy}
+	ver := openVersion(t, modfs.New(remote), "example.com/walk", "v1.0.0")
+
+	f, err := ver.ParseMod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Module.Mod.Path != "example.com/walk" {
+		t.Errorf("module: got %q", f.Module.Mod.Path)
+	}
+	if f.Go == nil || f.Go.Version != "1.22" {
+		t.Errorf("go: got %+v", f.Go)
+	}
+	var requires []string
+	for _, r := range f.Require {
+		requires = append(requires, fmt.Sprint(r.Mod, r.Indirect))
+	}
+	if want := []string{"example.com/a@v1.0.0 false", "example.com/b@v1.2.0 true"}; !slices.Equal(requires, want) {
+		t.Errorf("require: got %q, want %q", requires, want)
+	}
+	if len(f.Exclude) != 1 || f.Exclude[0].Mod.String() != "example.com/a@v0.9.0" {
+		t.Errorf("exclude: got %+v", f.Exclude)
+	}
+
+	// Cached
+	opened := len(remote.opened)
+	f2, err := ver.ParseMod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f2 != f || len(remote.opened) != opened {
+		t.Error("ParseMod result not cached")
+	}
+
+	bad := openVersion(t, modfs.New(proxy), "example.com/walk", "v1.1.0")
+	if _, err := bad.ParseMod(); err == nil || !strings.HasPrefix(err.Error(), "example.com/walk@v1.1.0/go.mod:") {
+		t.Errorf("invalid go.mod: got %v", err)
+	}
+}
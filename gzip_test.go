@@ -0,0 +1,68 @@
+package modfs_test
+
+import (
+	"compress/gzip"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/httpfs"
+)
+
+// gzipProxy serves the files of proxy with gzip Content-Encoding,
+// whatever the Accept-Encoding of the request.
+func gzipProxy(proxy fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := fs.ReadFile(proxy, strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write(data)
+		gz.Close()
+	})
+}
+
+func TestGzipMetadata(t *testing.T) {
+	const goMod = "module example.com/gz\n\ngo 1.24\n"
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/gz", "v1.2.3", map[string]string{
+		"go.mod": goMod,
+	})
+
+	server := httptest.NewServer(gzipProxy(proxy))
+	defer server.Close()
+
+	// A transport that doesn't request compression doesn't decompress transparently
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	hfs, err := httpfs.NewHTTPFS(client, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod, err := modfs.New(hfs).OpenModule("example.com/gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.Latest.Version != "v1.2.3" {
+		t.Errorf("@latest: got %q, want v1.2.3", mod.Latest.Version)
+	}
+
+	ver, err := mod.Version("v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ver.GoMod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != goMod {
+		t.Errorf(".mod: got %q, want %q", b, goMod)
+	}
+}
@@ -0,0 +1,90 @@
+package modfs_test
+
+import (
+	"encoding/json"
+	"io/fs"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/modfstest"
+)
+
+// addModule adds to proxy the files served for module path at version,
+// with the given files in the module zip.
+func addModule(t testing.TB, proxy fstest.MapFS, path, version string, files map[string]string) {
+	t.Helper()
+
+	zipFiles := make(fstest.MapFS, len(files))
+	for name, content := range files {
+		zipFiles[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	zipData, err := modfstest.Zip(path, version, zipFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := json.Marshal(map[string]any{
+		"Version": version,
+		"Time":    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	gomod, ok := files["go.mod"]
+	if !ok {
+		gomod = "module " + path + "\n"
+	}
+
+	proxy[path+"/@latest"] = &fstest.MapFile{Data: info}
+	proxy[path+"/@v/"+version+".info"] = &fstest.MapFile{Data: info}
+	proxy[path+"/@v/"+version+".mod"] = &fstest.MapFile{Data: []byte(gomod)}
+	proxy[path+"/@v/"+version+".zip"] = &fstest.MapFile{Data: zipData}
+	list := proxy[path+"/@v/list"]
+	if list == nil {
+		list = &fstest.MapFile{}
+		proxy[path+"/@v/list"] = list
+	}
+	list.Data = append(list.Data, version+"\n"...)
+}
+
+// streamFS hides the io.ReaderAt and io.Seeker capabilities of the files of
+// an FS, like a network filesystem would, and counts the bytes read.
+type streamFS struct {
+	fs.FS
+	read atomic.Int64
+}
+
+func (s *streamFS) Open(name string) (fs.File, error) {
+	f, err := s.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &streamFile{f: f, read: &s.read}, nil
+}
+
+type streamFile struct {
+	f    fs.File
+	read *atomic.Int64
+}
+
+func (f *streamFile) Stat() (fs.FileInfo, error) { return f.f.Stat() }
+func (f *streamFile) Close() error               { return f.f.Close() }
+
+func (f *streamFile) Read(b []byte) (int, error) {
+	n, err := f.f.Read(b)
+	f.read.Add(int64(n))
+	return n, err
+}
+
+func openVersion(t testing.TB, m *modfs.ModFS, path, version string) *modfs.Version {
+	t.Helper()
+	mod, err := m.OpenModule(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver, err := mod.Version(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ver
+}
@@ -0,0 +1,185 @@
+package httpfs
+
+import (
+	"context"
+	"errors"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dolmen-go/modfs/internal/dirents"
+)
+
+// maxIndexSize is the maximum size of the HTML listing of a directory read
+// by [HTTPFS.ReadDir].
+const maxIndexSize = 16 << 20
+
+// WithAutoIndex enables [HTTPFS.ReadDir], which lists directories by parsing
+// the HTML listings generated by static file servers (nginx autoindex,
+// Apache mod_autoindex...). This allows to use [fs.WalkDir] over compatible
+// servers.
+//
+// Not all servers list directories (the GOPROXY protocol doesn't), so this
+// is disabled by default.
+func WithAutoIndex() Option {
+	return func(h *HTTPFS) {
+		h.autoIndex = true
+	}
+}
+
+// hrefRegexp matches the links of an HTML document.
+var hrefRegexp = regexp.MustCompile(`(?i)<a\s[^>]*?\bhref\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// ReadDir implements [fs.ReadDirFS] by parsing the HTML listing of
+// directory name, which is requested with a trailing slash. The links to
+// the direct children of the directory are the entries: links to other
+// locations (parent directory, sorting links of Apache...) are ignored.
+// Links with a trailing slash are directories.
+//
+// The [fs.DirEntry.Info] of files sends a request (see [HTTPFS.Stat]) as the
+// listing doesn't hold the information in a standard format.
+//
+// If [WithAutoIndex] is not set, ReadDir fails with [fs.ErrPermission].
+func (h *HTTPFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return h.readDir(context.Background(), name)
+}
+
+func (h *HTTPFS) readDir(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if !h.autoIndex {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+	}
+	name = path.Clean(name)
+
+	dirURL := h.dirURL(name)
+	resp, err := h.do(ctx, http.MethodGet, dirURL.String(), nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	r, err := body(resp)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	defer r.Close()
+	if mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";"); !strings.EqualFold(strings.TrimSpace(mediaType), "text/html") {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory listing: " + mediaType)}
+	}
+	page, err := io.ReadAll(io.LimitReader(r, maxIndexSize))
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	// The listing may have been served after a redirect
+	return parseIndex(h, name, resp.Request.URL, page), nil
+}
+
+// dirURL returns the URL of the directory name, with a trailing slash.
+func (h *HTTPFS) dirURL(name string) *url.URL {
+	u := *h.base
+	p := strings.TrimSuffix(u.Path, "/")
+	if name != "." {
+		p += "/" + name
+	}
+	u.Path = p + "/"
+	u.RawPath = ""
+	return &u
+}
+
+// parseIndex extracts the entries of directory name from the links of page,
+// the HTML listing of dirURL. Entries are sorted by name.
+func parseIndex(h *HTTPFS, name string, dirURL *url.URL, page []byte) []fs.DirEntry {
+	var entries []fs.DirEntry
+	for _, m := range hrefRegexp.FindAllSubmatch(page, -1) {
+		href := string(m[1])
+		if href == "" {
+			href = string(m[2])
+		}
+		ref, err := url.Parse(html.UnescapeString(href))
+		if err != nil || ref.RawQuery != "" || (ref.Path == "" && ref.Opaque == "") {
+			continue
+		}
+		target := dirURL.ResolveReference(ref)
+		if target.Scheme != dirURL.Scheme || target.Host != dirURL.Host {
+			continue
+		}
+		child, ok := strings.CutPrefix(target.Path, dirURL.Path)
+		if !ok || child == "" {
+			continue
+		}
+		child, isDir := strings.CutSuffix(child, "/")
+		if !fs.ValidPath(child) || child == "." || strings.Contains(child, "/") {
+			continue
+		}
+		if slices.ContainsFunc(entries, func(e fs.DirEntry) bool { return e.Name() == child }) {
+			continue
+		}
+		entries = append(entries, &httpDirEntry{h: h, path: path.Join(name, child), dir: isDir})
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries
+}
+
+// httpDirEntry implements [fs.DirEntry] for the entries of an HTML listing.
+type httpDirEntry struct {
+	h    *HTTPFS
+	path string
+	dir  bool
+}
+
+func (e *httpDirEntry) Name() string { return path.Base(e.path) }
+func (e *httpDirEntry) IsDir() bool  { return e.dir }
+
+func (e *httpDirEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e *httpDirEntry) Info() (fs.FileInfo, error) {
+	if e.dir {
+		return &httpFileInfo{name: e.Name(), size: -1, dir: true}, nil
+	}
+	return e.h.Stat(e.path)
+}
+
+// httpDir implements [fs.ReadDirFile] for the directories opened with
+// [WithAutoIndex]. The listing is fetched by the first call to ReadDir.
+type httpDir struct {
+	h       *HTTPFS
+	ctx     context.Context
+	name    string
+	entries dirents.Entries
+	loaded  bool
+}
+
+func (d *httpDir) Stat() (fs.FileInfo, error) {
+	return &httpFileInfo{name: path.Base(d.name), size: -1, dir: true}, nil
+}
+
+func (d *httpDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *httpDir) Close() error { return nil }
+
+func (d *httpDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		entries, err := d.h.readDir(d.ctx, d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.loaded = entries, true
+	}
+	return d.entries.ReadDir(n)
+}
+
+var _ fs.ReadDirFS = (*HTTPFS)(nil)
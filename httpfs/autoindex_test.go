@@ -0,0 +1,143 @@
+package httpfs
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// nginxIndex is a listing in the format of the nginx autoindex module.
+const nginxIndex = `<html>
+<head><title>Index of /</title></head>
+<body>
+<h1>Index of /</h1><hr><pre><a href="../">../</a>
+<a href="sub/">sub/</a>                                               01-Jan-2024 00:00       -
+<a href="a%20b.txt">a b.txt</a>                                       01-Jan-2024 00:00       5
+<a href="file.txt">file.txt</a>                                       01-Jan-2024 00:00       5
+</pre><hr></body>
+</html>
+`
+
+// apacheIndex is a listing in the format of Apache mod_autoindex.
+const apacheIndex = `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">
+<html>
+ <head>
+  <title>Index of /sub</title>
+ </head>
+ <body>
+<h1>Index of /sub</h1>
+  <table>
+   <tr><th valign="top"><img src="/icons/blank.gif" alt="[ICO]"></th><th><a href="?C=N;O=D">Name</a></th><th><a href="?C=M;O=A">Last modified</a></th><th><a href="?C=S;O=A">Size</a></th></tr>
+   <tr><td valign="top"><img src="/icons/back.gif" alt="[PARENTDIR]"></td><td><a href="/">Parent Directory</a></td><td>&nbsp;</td><td align="right">  - </td></tr>
+   <tr><td valign="top"><img src="/icons/text.gif" alt="[TXT]"></td><td><a href="nested.txt">nested.txt</a></td><td align="right">2024-01-01 00:00  </td><td align="right">  6 </td></tr>
+   <tr><td valign="top"><img src="/icons/text.gif" alt="[TXT]"></td><td><a href="/sub/other.txt?x=&amp;y">other.txt</a></td><td align="right">2024-01-01 00:00  </td><td align="right">  6 </td></tr>
+   <tr><td valign="top"><img src="/icons/text.gif" alt="[TXT]"></td><td><a href='/sub/abs.txt'>abs.txt</a></td><td align="right">2024-01-01 00:00  </td><td align="right">  3 </td></tr>
+   <tr><td valign="top"><a href="https://httpd.apache.org/">Apache</a></td></tr>
+  </table>
+</body></html>
+`
+
+func newAutoIndexServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(nginxIndex))
+		case "/sub":
+			http.Redirect(w, r, "/sub/", http.StatusMovedPermanently)
+		case "/sub/":
+			w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+			w.Write([]byte(apacheIndex))
+		case "/file.txt", "/a b.txt":
+			w.Write([]byte("hello"))
+		case "/sub/nested.txt":
+			w.Write([]byte("nested"))
+		case "/sub/abs.txt":
+			w.Write([]byte("abs"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReadDir(t *testing.T) {
+	server := newAutoIndexServer(t)
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL, WithAutoIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var walked []string
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			path += "/"
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"./", "a b.txt", "file.txt", "sub/", "sub/abs.txt", "sub/nested.txt"}
+	if !slices.Equal(walked, want) {
+		t.Errorf("WalkDir: got %q, want %q", walked, want)
+	}
+
+	fi, err := fs.Stat(fsys, "sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.IsDir() || fi.Name() != "sub" {
+		t.Errorf("Stat(sub): got %s, want directory", fs.FormatFileInfo(fi))
+	}
+
+	f, err := fsys.Open("sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		t.Fatalf("Open(sub): got %T, want fs.ReadDirFile", f)
+	}
+	entries, err := dir.ReadDir(1)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "abs.txt" {
+		t.Fatalf("ReadDir(1): got %v, %v", entries, err)
+	}
+	info, err := entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 3 {
+		t.Errorf("Info().Size() = %d, want 3", info.Size())
+	}
+
+	// The content of files is not a listing
+	if _, err := fs.ReadDir(fsys, "file.txt"); err == nil {
+		t.Error("ReadDir(file.txt): got nil error")
+	}
+}
+
+func TestReadDirDisabled(t *testing.T) {
+	server := newAutoIndexServer(t)
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.ReadDir("."); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("ReadDir: got %v, want %v", err, fs.ErrPermission)
+	}
+	if _, err := fsys.Open("."); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Open: got %v, want %v", err, fs.ErrPermission)
+	}
+}
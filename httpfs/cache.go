@@ -0,0 +1,165 @@
+package httpfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"sync"
+)
+
+// maxCachedSize is the maximum size of the content stored in an [HTTPCache].
+// Larger resources (such as the zip archives of modules) are not cached.
+const maxCachedSize = 1 << 20
+
+// HTTPCache stores the content of resources fetched by an [HTTPFS] with
+// their validators (ETag, Last-Modified), for conditional requests: the
+// cached content is served when the server replies 304 Not Modified.
+//
+// See [WithCache]. Implementations must be safe for concurrent use.
+type HTTPCache interface {
+	// Get returns the response stored for url.
+	Get(url string) (*CachedResponse, bool)
+	// Put stores resp for url. resp must not be modified after the call.
+	Put(url string, resp *CachedResponse)
+}
+
+// CachedResponse is a response stored in an [HTTPCache].
+type CachedResponse struct {
+	ETag         string // ETag header, sent back as If-None-Match
+	LastModified string // Last-Modified header, sent back as If-Modified-Since
+	ContentType  string
+	Body         []byte // decoded content
+}
+
+// WithCache sets the cache of responses: resources served with an ETag or a
+// Last-Modified header are stored in c, and requested again with
+// If-None-Match or If-Modified-Since. This reduces the bandwidth used for
+// resources accessed repeatedly, such as the @latest of modules.
+//
+// Only the responses smaller than 1 MiB are cached. Files served from the
+// cache implement [io.ReaderAt] and [io.Seeker].
+func WithCache(c HTTPCache) Option {
+	return func(h *HTTPFS) {
+		h.cache = c
+	}
+}
+
+// MemoryCache is an [HTTPCache] that keeps responses in memory, without
+// eviction.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*CachedResponse
+}
+
+// NewMemoryCache returns an empty [MemoryCache].
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]*CachedResponse)}
+}
+
+// Get implements [HTTPCache].
+func (c *MemoryCache) Get(url string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.entries[url]
+	return resp, ok
+}
+
+// Put implements [HTTPCache].
+func (c *MemoryCache) Put(url string, resp *CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = resp
+}
+
+// openCached sends a conditional request for f if its response is in the
+// cache, and serves the cached content if the server replies 304 Not
+// Modified. Cacheable responses are read fully and stored.
+//
+// It returns a nil file (and no error) if the response is not cacheable:
+// resp must then be handled by the caller.
+func (f *httpFile) openCached() (fs.File, *http.Response, error) {
+	cached, ok := f.h.cache.Get(f.url)
+	var header http.Header
+	if ok {
+		header = make(http.Header)
+		if cached.ETag != "" {
+			header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := f.h.do(f.ctx, http.MethodGet, f.url, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		ri := newResponseInfo(resp)
+		ri.ContentType = cached.ContentType
+		return f.cachedFile(cached, ri), nil, nil
+	}
+
+	cached = &CachedResponse{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+	}
+	if (cached.ETag == "" && cached.LastModified == "") || resp.ContentLength > maxCachedSize || f.h.isDir(resp) {
+		return nil, resp, nil
+	}
+	r, err := body(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The size is unknown if the content is compressed
+	b, err := io.ReadAll(io.LimitReader(r, maxCachedSize+1))
+	if err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+	if len(b) > maxCachedSize {
+		// Too large to be cached: serve what has been read, then the rest
+		f.size = -1
+		f.resp = newResponseInfo(resp)
+		f.modTime = lastModified(resp)
+		f.reader = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), r), r}
+		return f, nil, nil
+	}
+	r.Close()
+	cached.Body = b
+	f.h.cache.Put(f.url, cached)
+	return f.cachedFile(cached, newResponseInfo(resp)), nil, nil
+}
+
+// cachedFile returns the file serving the content of cached, for the
+// response described by resp.
+func (f *httpFile) cachedFile(cached *CachedResponse, resp *ResponseInfo) *memFile {
+	modTime, _ := http.ParseTime(cached.LastModified)
+	return &memFile{
+		Reader: bytes.NewReader(cached.Body),
+		info: httpFileInfo{
+			name:    f.name,
+			size:    int64(len(cached.Body)),
+			modTime: modTime,
+			resp:    resp,
+		},
+	}
+}
+
+// memFile is a file whose content is in memory, such as a response served
+// from an [HTTPCache].
+type memFile struct {
+	*bytes.Reader
+	info httpFileInfo
+}
+
+// ContentType returns the Content-Type header of the response.
+func (f *memFile) ContentType() string { return f.info.resp.ContentType }
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return &f.info, nil }
+func (f *memFile) Close() error               { return nil }
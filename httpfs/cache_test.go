@@ -0,0 +1,141 @@
+package httpfs
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	const lastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+	content := `{"Version":"v1.0.0"}`
+	etag := `"v1"`
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/last-modified":
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/no-validator":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL, WithCache(NewMemoryCache()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name, want string) {
+		t.Helper()
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: got %q, want %q", name, b, want)
+		}
+		if ct := f.(interface{ ContentType() string }).ContentType(); ct != "application/json" {
+			t.Errorf("%s: ContentType() = %q", name, ct)
+		}
+	}
+
+	for _, name := range []string{"etag", "last-modified", "no-validator"} {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			notModified.Store(0)
+			read(name, content)
+			read(name, content)
+			if n := requests.Load(); n != 2 {
+				t.Errorf("requests: got %d, want 2", n)
+			}
+			wantNotModified := int32(1)
+			if name == "no-validator" {
+				wantNotModified = 0
+			}
+			if n := notModified.Load(); n != wantNotModified {
+				t.Errorf("304 responses: got %d, want %d", n, wantNotModified)
+			}
+		})
+	}
+
+	// Modified content replaces the cached content
+	content, etag = `{"Version":"v1.1.0"}`, `"v2"`
+	read("etag", content)
+	read("etag", content)
+
+	fi, err := fs.Stat(fsys, "last-modified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("last-modified")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfi.ModTime().Equal(fi.ModTime()) || cfi.Name() != "last-modified" {
+		t.Errorf("Stat of cached file: got %s, want %s", fs.FormatFileInfo(cfi), fs.FormatFileInfo(fi))
+	}
+	if _, ok := f.(io.ReaderAt); !ok {
+		t.Error("cached file doesn't implement io.ReaderAt")
+	}
+}
+
+func TestCacheLarge(t *testing.T) {
+	content := strings.Repeat("x", maxCachedSize+1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"large"`)
+		if r.Header.Get("If-None-Match") != "" {
+			t.Error("conditional request for uncached content")
+		}
+		// Chunked: the size is unknown
+		io.WriteString(w, content[:10])
+		w.(http.Flusher).Flush()
+		io.WriteString(w, content[10:])
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL, WithCache(NewMemoryCache()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		b, err := fs.ReadFile(fsys, "large.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("got %d bytes, want %d", len(b), len(content))
+		}
+	}
+}
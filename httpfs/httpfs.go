@@ -2,6 +2,11 @@
 package httpfs
 
 import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,18 +14,136 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 // HTTPFS implements an [io/fs.FS] that accesses remote resources via HTTP.
 type HTTPFS struct {
-	client *http.Client
-	base   *url.URL
+	client       *http.Client
+	base         *url.URL
+	statusMapper func(status int) error
+	allowOffsite bool
+	header       http.Header
+	requestHook  func(*http.Request)
+	retry        RetryPolicy
+	autoIndex    bool
+	cache        HTTPCache
+	userAgent    string
+}
+
+// DefaultUserAgent is the User-Agent header sent by [HTTPFS], unless changed
+// with [WithUserAgent]. Some proxies and gateways reject or throttle the
+// default user agent of Go's HTTP client.
+const DefaultUserAgent = "modfs/1 (+https://github.com/dolmen-go/modfs)"
+
+// ErrUnauthorized is returned by [HTTPFS.Open] when the server redirected
+// the request to another host, such as the login page of an SSO: the content
+// received is not the requested resource. See [AllowOffsiteRedirects].
+var ErrUnauthorized = errors.New("unauthorized: redirected to another host")
+
+// Option configures an [HTTPFS].
+type Option func(*HTTPFS)
+
+// WithRetry sets the policy p for retrying requests, instead of
+// [DefaultRetryPolicy]. Zero fields of p are replaced by values from
+// DefaultRetryPolicy.
+func WithRetry(p RetryPolicy) Option {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay == 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	return func(h *HTTPFS) {
+		h.retry = p
+	}
+}
+
+// WithoutRetry disables the retry of requests, for fail-fast behavior or if
+// the Transport of the client already retries requests.
+func WithoutRetry() Option {
+	return func(h *HTTPFS) {
+		h.retry = RetryPolicy{MaxAttempts: 1}
+	}
+}
+
+// WithStatusMapper sets a function that maps the HTTP status of non-OK
+// responses to errors, such as [fs.ErrNotExist] or [fs.ErrPermission].
+// This allows to handle non-standard statuses of some servers (451, 403 for
+// private resources...).
+//
+// The error returned by mapper wraps an [*HTTPError] with the status.
+// If mapper returns nil, the default mapping applies: the error is an
+// *HTTPError, which matches [fs.ErrNotExist] for 404 and 410.
+func WithStatusMapper(mapper func(status int) error) Option {
+	return func(h *HTTPFS) {
+		h.statusMapper = mapper
+	}
+}
+
+// AllowOffsiteRedirects allows the server to redirect requests to other hosts,
+// such as a CDN serving the files. By default, [HTTPFS.Open] fails with
+// [ErrUnauthorized] if the final response doesn't come from the host of the
+// base URL.
+func AllowOffsiteRedirects() Option {
+	return func(h *HTTPFS) {
+		h.allowOffsite = true
+	}
+}
+
+// WithHeader adds a header sent with every request, such as an
+// Authorization header for a private proxy:
+//
+//	httpfs.WithHeader("Authorization", "Bearer "+token)
+//
+// Multiple values can be set for the same key.
+func WithHeader(key, value string) Option {
+	return func(h *HTTPFS) {
+		if h.header == nil {
+			h.header = make(http.Header)
+		}
+		h.header.Add(key, value)
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request, instead
+// of [DefaultUserAgent]. If ua is empty, no User-Agent header is sent.
+//
+// A User-Agent set with [WithHeader] takes precedence.
+func WithUserAgent(ua string) Option {
+	return func(h *HTTPFS) {
+		h.userAgent = ua
+	}
+}
+
+// WithRequestHook sets a function called on every request just before it is
+// sent (after the headers of [WithHeader] are set), for customization such
+// as signing. The hook is not called for the requests following redirects:
+// use the Transport of the client for that.
+func WithRequestHook(hook func(*http.Request)) Option {
+	return func(h *HTTPFS) {
+		h.requestHook = hook
+	}
 }
 
 // NewHTTPFS creates a new filesystem that accesses resources via HTTP.
 // The baseURL parameter specifies the root of the remote filesystem.
-func NewHTTPFS(client *http.Client, baseURL string) (*HTTPFS, error) {
+//
+// All requests are sent through client, so customization of requests
+// (authentication, mutual TLS, tracing...) can be layered in the
+// [http.RoundTripper] of its Transport.
+//
+// Transient failures are retried according to [DefaultRetryPolicy] (see
+// [RetryTransport]), unless changed with [WithRetry] or disabled with
+// [WithoutRetry]: the Transport of client is wrapped with a RetryTransport.
+// The client given is not modified.
+func NewHTTPFS(client *http.Client, baseURL string, opts ...Option) (*HTTPFS, error) {
 	if client == nil {
 		panic(errors.New("client cannot be nil"))
 	}
@@ -33,49 +156,325 @@ func NewHTTPFS(client *http.Client, baseURL string) (*HTTPFS, error) {
 		return nil, errors.New("invalid base URL: no fragment allowed")
 	}
 
-	return &HTTPFS{
-		client: client,
-		base:   base,
-	}, nil
+	h := &HTTPFS{
+		client:    client,
+		base:      base,
+		retry:     DefaultRetryPolicy,
+		userAgent: DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.retry.MaxAttempts >= 2 {
+		c := *h.client
+		c.Transport = NewRetryTransport(c.Transport, h.retry)
+		h.client = &c
+	}
+	return h, nil
 }
 
 // Open implements [fs.FS].
+//
+// Files implement [io.Seeker]. If the server advertises support of Range
+// requests (Accept-Ranges: bytes) and the size of the content is known, files
+// also implement [io.ReaderAt]: a zip archive can then be read without
+// downloading it fully.
 func (h *HTTPFS) Open(name string) (fs.File, error) {
+	return h.OpenContext(context.Background(), name)
+}
+
+// OpenContext is like [HTTPFS.Open], but the request is bound to ctx:
+// cancelling ctx interrupts the request and the reading of the file.
+func (h *HTTPFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
 	}
 
 	name = path.Clean(name)
 	if name == "." {
+		if h.autoIndex {
+			return &httpDir{h: h, ctx: ctx, name: name}, nil
+		}
 		// return unreadableDir("."), nil
 		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
 	}
 
-	// Construct the full URL
-	fullURL := *h.base
-	fullURL.Path = path.Join(fullURL.Path, name)
-
-	// Make the request
-	resp, err := h.client.Get(fullURL.String())
+	file := &httpFile{
+		h:    h,
+		ctx:  ctx,
+		url:  h.url(name),
+		name: path.Base(name),
+	}
+	var resp *http.Response
+	var err error
+	if h.cache != nil {
+		var f fs.File
+		if f, resp, err = file.openCached(); f != nil {
+			return f, nil
+		}
+	} else {
+		resp, err = file.get(0, -1)
+	}
 	if err != nil {
 		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
 	}
+	if h.isDir(resp) {
+		resp.Body.Close()
+		return &httpDir{h: h, ctx: ctx, name: name}, nil
+	}
+	file.size = resp.ContentLength
+	file.resp = newResponseInfo(resp)
+	file.modTime = lastModified(resp)
+	file.acceptRanges = resp.Header.Get("Accept-Ranges") == "bytes"
 
-	if resp.StatusCode == http.StatusNotFound {
+	if file.reader, err = body(resp); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if file.reader != resp.Body {
+		file.size = -1            // Size of the decompressed content is unknown
+		file.acceptRanges = false // Ranges apply to the compressed content
+	}
+
+	if file.acceptRanges && file.size >= 0 {
+		return &httpRangeFile{httpFile: file}, nil
+	}
+	return file, nil
+}
+
+// body returns the content of the response, decompressed if the server
+// sent it compressed with the gzip or deflate Content-Encoding.
+func body(resp *http.Response) (io.ReadCloser, error) {
+	// The transport decompresses transparently only if it requested compression itself.
+	if resp.Uncompressed {
+		return resp.Body, nil
+	}
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		r = gz
+	case "deflate":
+		var err error
+		if r, err = newDeflateReader(resp.Body); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	default:
+		return resp.Body, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, resp.Body}, nil
+}
+
+// newDeflateReader decodes the deflate Content-Encoding, which is the zlib
+// format (RFC 1950). As some servers send raw deflate data (RFC 1951)
+// instead, the zlib header is checked before choosing the decoder.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// get sends a GET request for the resource of f. If off > 0 or end >= 0,
+// the bytes from off to end (included; to the end of the content if end is
+// negative) are requested with a Range request. The response is either 200
+// (full content) or 206 (partial content).
+func (f *httpFile) get(off, end int64) (*http.Response, error) {
+	var rangeHeader string
+	if end >= 0 {
+		rangeHeader = fmt.Sprintf("bytes=%d-%d", off, end)
+	} else if off > 0 {
+		rangeHeader = fmt.Sprintf("bytes=%d-", off)
+	}
+	var header http.Header
+	if rangeHeader != "" {
+		header = http.Header{"Range": {rangeHeader}}
+	}
+	resp, err := f.h.do(f.ctx, http.MethodGet, f.url, header)
+	if err != nil {
+		return nil, err // Drop the response of status errors
+	}
+	return resp, nil
+}
+
+// url returns the URL of the resource name.
+func (h *HTTPFS) url(name string) string {
+	u := *h.base
+	u.Path = path.Join(u.Path, name)
+	return u.String()
+}
+
+// do sends a request with the given header and the headers set by options.
+// The response status is either 200, 206 if header has a Range, or 304 if
+// header has a conditional header (If-None-Match, If-Modified-Since).
+//
+// For other statuses, the error is mapped from the status and the response
+// is also returned, with its body closed.
+func (h *HTTPFS) do(ctx context.Context, method, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if _, ok := h.header["User-Agent"]; !ok {
+		// An empty value prevents the Transport from sending its default
+		req.Header["User-Agent"] = []string{h.userAgent}
+	}
+	for key, values := range h.header {
+		req.Header[key] = append(req.Header[key], values...)
+	}
+	if h.requestHook != nil {
+		h.requestHook(req)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !h.allowOffsite && !sameHost(resp.Request.URL, h.base) {
 		resp.Body.Close()
-		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		return nil, fmt.Errorf("%w (%s)", ErrUnauthorized, resp.Request.URL.Host)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusPartialContent && header.Get("Range") != "":
+	case resp.StatusCode == http.StatusNotModified && (header.Get("If-None-Match") != "" || header.Get("If-Modified-Since") != ""):
+	default:
 		resp.Body.Close()
-		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("HTTP status %d", resp.StatusCode)}
+		return resp, h.statusError(resp)
 	}
+	return resp, nil
+}
 
-	return &httpFile{
-		reader: resp.Body,
-		size:   resp.ContentLength,
-		name:   path.Base(name),
-	}, nil
+// sameHost reports whether u and base designate the same host: host names
+// are compared case-insensitively and a missing port is the default port of
+// the scheme.
+func sameHost(u, base *url.URL) bool {
+	return strings.EqualFold(u.Hostname(), base.Hostname()) && port(u) == port(base)
+}
+
+// port returns the port of u, or the default port of its scheme.
+func port(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// Stat implements [fs.StatFS] with a HEAD request: the content is not
+// downloaded. If the server rejects HEAD requests (status 405 or 501), a
+// GET request is sent instead and its body is closed immediately.
+func (h *HTTPFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	name = path.Clean(name)
+	if name == "." {
+		if h.autoIndex {
+			return &httpFileInfo{name: name, size: -1, dir: true}, nil
+		}
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrPermission}
+	}
+
+	url := h.url(name)
+	resp, err := h.do(context.Background(), http.MethodHead, url, nil)
+	if err != nil && resp != nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = h.do(context.Background(), http.MethodGet, url, nil)
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	resp.Body.Close()
+	if h.isDir(resp) {
+		return &httpFileInfo{name: path.Base(name), size: -1, dir: true}, nil
+	}
+
+	fi := &httpFileInfo{
+		name:    path.Base(name),
+		size:    resp.ContentLength,
+		modTime: lastModified(resp),
+		resp:    newResponseInfo(resp),
+	}
+	if resp.Header.Get("Content-Encoding") != "" {
+		fi.size = -1 // Size of the decoded content is unknown
+	}
+	return fi, nil
+}
+
+// isDir reports whether resp is the listing of a directory, with
+// [WithAutoIndex]: servers redirect the URL of directories to the URL with
+// a trailing slash.
+func (h *HTTPFS) isDir(resp *http.Response) bool {
+	return h.autoIndex && strings.HasSuffix(resp.Request.URL.Path, "/")
+}
+
+// lastModified returns the time of the Last-Modified header of resp, or the
+// zero time if the header is absent or invalid.
+func lastModified(resp *http.Response) time.Time {
+	t, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// HTTPError is the error for a response with an unexpected HTTP status.
+// It is wrapped in the [*fs.PathError] returned by [HTTPFS] methods:
+//
+//	var httpErr *httpfs.HTTPError
+//	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusForbidden {
+//		...
+//	}
+type HTTPError struct {
+	StatusCode int
+	Status     string // such as "404 Not Found"
+	URL        string // URL of the response, after redirects
+}
+
+func (e *HTTPError) Error() string {
+	return "HTTP status " + e.Status
+}
+
+// Is reports whether the status means that the resource doesn't exist: 404
+// Not Found, or 410 Gone (used by Go module proxies for modules or versions
+// which are not available), match [fs.ErrNotExist].
+func (e *HTTPError) Is(target error) bool {
+	return target == fs.ErrNotExist && (e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone)
+}
+
+// statusError maps the status of a non-OK response to an error.
+func (h *HTTPFS) statusError(resp *http.Response) error {
+	httpErr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		URL:        resp.Request.URL.String(),
+	}
+	if httpErr.Status == "" {
+		httpErr.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	}
+	if h.statusMapper != nil {
+		if err := h.statusMapper(resp.StatusCode); err != nil {
+			return fmt.Errorf("%w: %w", err, httpErr)
+		}
+	}
+	return httpErr
 }
 
 // unreadableDir implements [fs.File] and [fs.ReadDirFile] but denies reading entries.
@@ -96,35 +495,269 @@ func (ud unreadableDir) ReadDir(n int) ([]fs.DirEntry, error) {
 }
 
 type httpFile struct {
-	reader io.ReadCloser
-	size   int64
-	name   string
-	offset int64
+	h   *HTTPFS
+	ctx context.Context
+	url string
+
+	reader       io.ReadCloser // nil after Seek, until the next Read
+	size         int64
+	name         string
+	offset       int64         // offset of the next Read
+	readerOffset int64         // offset of reader in the content
+	resp         *ResponseInfo // initial response
+	modTime      time.Time     // from Last-Modified
+	acceptRanges bool          // the server supports Range requests
+	closed       bool
+}
+
+// ContentType returns the Content-Type header of the response, which is
+// the media type of the file as reported by the server.
+func (f *httpFile) ContentType() string {
+	return f.resp.ContentType
 }
 
 func (f *httpFile) Read(b []byte) (int, error) {
-	return f.reader.Read(b)
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+	if err := f.ctx.Err(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	if f.size >= 0 && f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if err := f.sync(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	n, err := f.reader.Read(b)
+	f.offset += int64(n)
+	f.readerOffset = f.offset
+	if err != nil && err != io.EOF && f.ctx.Err() != nil {
+		// Report the cancellation instead of the error of the transport
+		err = &fs.PathError{Op: "read", Path: f.name, Err: f.ctx.Err()}
+	}
+	return n, err
+}
+
+// maxDiscard is the maximum number of bytes skipped by reading (instead of
+// sending a Range request) to move forward after [httpFile.Seek].
+const maxDiscard = 32 << 10
+
+// sync moves the reader to the offset set by Seek. Forward moves read and
+// discard the content if the server doesn't support Range requests or if
+// the gap is small. Else a new request is sent, from the start of the
+// content if the server doesn't support Range requests.
+func (f *httpFile) sync() error {
+	if f.reader != nil && f.offset == f.readerOffset {
+		return nil
+	}
+	if f.reader == nil || f.offset < f.readerOffset || (f.acceptRanges && f.offset-f.readerOffset > maxDiscard) {
+		if f.reader != nil {
+			f.reader.Close()
+			f.reader = nil
+		}
+		var off int64
+		if f.acceptRanges {
+			off = f.offset
+		}
+		resp, err := f.get(off, -1)
+		if err != nil {
+			return err
+		}
+		if f.reader, err = body(resp); err != nil {
+			return err
+		}
+		f.readerOffset = 0
+		if resp.StatusCode == http.StatusPartialContent {
+			f.readerOffset = off
+		} else {
+			f.acceptRanges = false // Range ignored by the server
+		}
+	}
+	n, err := io.CopyN(io.Discard, f.reader, f.offset-f.readerOffset)
+	f.readerOffset += n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Seek implements [io.Seeker]. The request to the new offset is sent by the
+// next Read.
+//
+// If the server supports Range requests (Accept-Ranges: bytes), the content
+// is requested from the new offset. Else the content is read again from the
+// start (for moves backward) and discarded up to the offset.
+// Seeking relative to the end requires the size of the content to be known.
+func (f *httpFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		if f.size < 0 {
+			return 0, &fs.PathError{Op: "seek", Path: f.name, Err: errors.New("unknown size")}
+		}
+		offset += f.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
 }
 
 func (f *httpFile) Close() error {
-	return f.reader.Close()
+	f.closed = true
+	if f.reader == nil {
+		return nil
+	}
+	err := f.reader.Close()
+	f.reader = nil
+	return err
 }
 
 func (f *httpFile) Stat() (fs.FileInfo, error) {
 	return &httpFileInfo{
-		name: f.name,
-		size: f.size,
+		name:    f.name,
+		size:    f.size,
+		modTime: f.modTime,
+		resp:    f.resp,
 	}, nil
 }
 
+// readAtBlockSize is the minimum size of the Range requests sent by
+// [httpRangeFile.ReadAt], to limit the number of requests for the small
+// reads of [archive/zip].
+const readAtBlockSize = 64 << 10
+
+// httpRangeFile is an [httpFile] of known size served by a server which
+// supports Range requests. It implements [io.ReaderAt], so remote zip
+// archives can be opened without downloading them fully.
+type httpRangeFile struct {
+	*httpFile
+
+	mu       sync.Mutex
+	block    []byte // last block fetched by ReadAt
+	blockOff int64  // offset of block in the content
+}
+
+// ReadAt implements [io.ReaderAt] with Range requests. The last block
+// fetched is kept, so contiguous small reads don't send a request each.
+//
+// Concurrent calls are safe, but serialized.
+func (f *httpRangeFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, &fs.PathError{Op: "readat", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.offset == 0 && f.readerOffset == 0 && f.reader != nil {
+		// Release the connection of the initial request, not read yet.
+		// Read would send a new request.
+		f.reader.Close()
+		f.reader = nil
+	}
+
+	n := 0
+	for n < len(p) && off < f.size {
+		if off < f.blockOff || off >= f.blockOff+int64(len(f.block)) {
+			if err := f.fetch(off, max(len(p)-n, readAtBlockSize)); err != nil {
+				return n, &fs.PathError{Op: "readat", Path: f.name, Err: err}
+			}
+		}
+		c := copy(p[n:], f.block[off-f.blockOff:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close closes the file. It waits for a pending ReadAt.
+func (f *httpRangeFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.block = nil
+	return f.httpFile.Close()
+}
+
+// fetch fetches the block of size bytes (truncated to the end of the
+// content) at offset off.
+func (f *httpRangeFile) fetch(off int64, size int) error {
+	end := min(off+int64(size), f.size) - 1
+	resp, err := f.get(off, end)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return errors.New("server ignored the Range request")
+	}
+	block := make([]byte, end-off+1)
+	if _, err := io.ReadFull(resp.Body, block); err != nil {
+		return err
+	}
+	f.block, f.blockOff = block, off
+	return nil
+}
+
+// ResponseInfo describes the HTTP response of a file of an [HTTPFS]. It is
+// returned by the Sys method of the [fs.FileInfo] of the files (from
+// [HTTPFS.Stat] and from the Stat method of the opened files), to help
+// debugging misrouted requests:
+//
+//	if resp, ok := fi.Sys().(*httpfs.ResponseInfo); ok {
+//		log.Println(resp.StatusCode, resp.URL, resp.ContentType)
+//	}
+//
+// Directories (see [WithAutoIndex]) have no ResponseInfo.
+type ResponseInfo struct {
+	StatusCode  int    // 304 (Not Modified) for the content of an HTTPCache
+	ContentType string // Content-Type header
+	URL         string // Final URL, after redirects
+}
+
+func newResponseInfo(resp *http.Response) *ResponseInfo {
+	return &ResponseInfo{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		URL:         resp.Request.URL.String(),
+	}
+}
+
+// httpFileInfo implements [fs.FileInfo] for the resources of an [HTTPFS].
 type httpFileInfo struct {
-	name string
-	size int64
+	name    string // base name, not the path given to Open or Stat
+	size    int64
+	modTime time.Time
+	dir     bool          // see WithAutoIndex
+	resp    *ResponseInfo // nil for directories
 }
 
-func (fi *httpFileInfo) Name() string       { return fi.name }
-func (fi *httpFileInfo) Size() int64        { return fi.size }
-func (fi *httpFileInfo) Mode() fs.FileMode  { return 0444 } // read-only
-func (fi *httpFileInfo) ModTime() time.Time { return time.Time{} }
-func (fi *httpFileInfo) IsDir() bool        { return false }
-func (fi *httpFileInfo) Sys() interface{}   { return nil }
+func (fi *httpFileInfo) Name() string { return fi.name }
+func (fi *httpFileInfo) Size() int64  { return fi.size }
+func (fi *httpFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444 // read-only
+}
+func (fi *httpFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *httpFileInfo) IsDir() bool        { return fi.dir }
+
+// Sys returns the [ResponseInfo] of the file, nil for directories.
+func (fi *httpFileInfo) Sys() interface{} {
+	if fi.resp == nil {
+		return nil
+	}
+	return fi.resp
+}
@@ -1,10 +1,27 @@
 package httpfs
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestHTTPFS(t *testing.T) {
@@ -14,6 +31,8 @@ func TestHTTPFS(t *testing.T) {
 		case "/test.txt":
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte("hello world"))
+		case "/dir/nested.txt":
+			w.Write([]byte("nested"))
 		case "/notfound":
 			w.WriteHeader(http.StatusNotFound)
 		case "/error":
@@ -36,6 +55,12 @@ func TestHTTPFS(t *testing.T) {
 			wantErr: false,
 			want:    "hello world",
 		},
+		{
+			name:    "nested file",
+			path:    "dir/nested.txt",
+			wantErr: false,
+			want:    "nested",
+		},
 		{
 			name:    "not found",
 			path:    "notfound",
@@ -88,8 +113,9 @@ func TestHTTPFS(t *testing.T) {
 				return
 			}
 
-			if info.Name() != tt.path {
-				t.Errorf("Name() = %v, want %v", info.Name(), tt.path)
+			// Base name, as required by fs.FileInfo
+			if want := path.Base(tt.path); info.Name() != want {
+				t.Errorf("Name() = %v, want %v", info.Name(), want)
 			}
 			if info.IsDir() {
 				t.Error("IsDir() = true, want false")
@@ -128,3 +154,801 @@ func TestNewHTTPFS_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestWithStatusMapper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/private":
+			w.WriteHeader(http.StatusForbidden)
+		case "/legal":
+			w.WriteHeader(http.StatusUnavailableForLegalReasons)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	errLegal := errors.New("unavailable for legal reasons")
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL, WithStatusMapper(func(status int) error {
+		switch status {
+		case http.StatusForbidden:
+			return fs.ErrPermission
+		case http.StatusUnavailableForLegalReasons:
+			return errLegal
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]error{
+		"private": fs.ErrPermission,
+		"legal":   errLegal,
+		"missing": fs.ErrNotExist, // default mapping
+	} {
+		_, err := fsys.Open(name)
+		if !errors.Is(err, want) {
+			t.Errorf("%s: got %v, want %v", name, err, want)
+		}
+		// The status is still available
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Errorf("%s: %v doesn't wrap an *HTTPError", name, err)
+		}
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(path.Base(r.URL.Path))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL, WithoutRetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		status   int
+		notExist bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusGone, true},
+		{http.StatusForbidden, false},
+		{http.StatusInternalServerError, false},
+	} {
+		name := "status/" + strconv.Itoa(tt.status)
+		_, err := fsys.Open(name)
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Errorf("%s: got %v, want *HTTPError", name, err)
+			continue
+		}
+		if httpErr.StatusCode != tt.status {
+			t.Errorf("%s: StatusCode = %d", name, httpErr.StatusCode)
+		}
+		if want := strconv.Itoa(tt.status) + " " + http.StatusText(tt.status); httpErr.Status != want {
+			t.Errorf("%s: Status = %q, want %q", name, httpErr.Status, want)
+		}
+		if httpErr.URL != server.URL+"/"+name {
+			t.Errorf("%s: URL = %q", name, httpErr.URL)
+		}
+		if errors.Is(err, fs.ErrNotExist) != tt.notExist {
+			t.Errorf("%s: errors.Is(%v, fs.ErrNotExist) = %t", name, err, !tt.notExist)
+		}
+
+		_, err = fsys.Stat(name)
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.status {
+			t.Errorf("Stat(%s): got %v", name, err)
+		}
+	}
+}
+
+func TestOffsiteRedirect(t *testing.T) {
+	login := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>Please log in</html>"))
+	}))
+	defer login.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/private":
+			http.Redirect(w, r, login.URL+"/login", http.StatusFound)
+		case "/moved":
+			http.Redirect(w, r, "/file", http.StatusMovedPermanently)
+		case "/file":
+			w.Write([]byte("content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Open("private"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("private: got %v, want ErrUnauthorized", err)
+	}
+	// Redirects on the same host are fine
+	if _, err := fs.ReadFile(fsys, "moved"); err != nil {
+		t.Errorf("moved: %v", err)
+	}
+
+	fsys, err = NewHTTPFS(http.DefaultClient, server.URL, AllowOffsiteRedirects())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile(fsys, "private"); err != nil {
+		t.Errorf("private: %v", err)
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	for _, tc := range []struct {
+		u, base string
+		want    bool
+	}{
+		{"https://proxy.example.com/a", "https://proxy.example.com/", true},
+		{"https://PROXY.Example.com/a", "https://proxy.example.com/", true},
+		{"https://proxy.example.com:443/a", "https://proxy.example.com/", true},
+		{"https://proxy.example.com/a", "https://proxy.example.com:443/", true},
+		{"http://proxy.example.com:80/a", "http://proxy.example.com/", true},
+		{"http://[::1]:8080/a", "http://[::1]:8080/", true},
+		{"https://proxy.example.com:8443/a", "https://proxy.example.com/", false},
+		{"http://proxy.example.com/a", "https://proxy.example.com/", false},
+		{"https://login.example.com/a", "https://proxy.example.com/", false},
+	} {
+		u, _ := url.Parse(tc.u)
+		base, _ := url.Parse(tc.base)
+		if got := sameHost(u, base); got != tc.want {
+			t.Errorf("sameHost(%s, %s) = %t, want %t", tc.u, tc.base, got, tc.want)
+		}
+	}
+}
+
+// bearerTransport injects a bearer token in requests.
+type bearerTransport struct {
+	base  http.RoundTripper
+	token string
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+func TestCustomTransport(t *testing.T) {
+	const token = "s3cr3t"
+	var failed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// Fail the first authorized request to check the composition with WithRetry
+		if !failed {
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &bearerTransport{base: http.DefaultTransport, token: token}}
+	fsys, err := NewHTTPFS(client, server.URL, WithRetry(RetryPolicy{BaseDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fsys, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "content" {
+		t.Errorf("got %q", b)
+	}
+	if !failed {
+		t.Error("request not retried")
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join(r.Header.Values("User-Agent"), ",")))
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, DefaultUserAgent},
+		{"custom", []Option{WithUserAgent("mytool/2.0")}, "mytool/2.0"},
+		{"disabled", []Option{WithUserAgent("")}, ""},
+		{"header", []Option{WithUserAgent("mytool/2.0"), WithHeader("User-Agent", "other/1.0")}, "other/1.0"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, err := NewHTTPFS(http.DefaultClient, server.URL, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := fs.ReadFile(fsys, "ua")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("User-Agent: got %q, want %q", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write([]byte("PK"))
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("file.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ct, ok := f.(interface{ ContentType() string })
+	if !ok {
+		t.Fatal("ContentType not implemented")
+	}
+	if got := ct.ContentType(); got != "application/zip" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestResponseInfo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old/", http.RedirectHandler("/new/m.info", http.StatusFound))
+	mux.HandleFunc("/new/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	check := func(t *testing.T, fi fs.FileInfo, wantStatus int) {
+		t.Helper()
+		resp, ok := fi.Sys().(*ResponseInfo)
+		if !ok {
+			t.Fatalf("Sys: got %T", fi.Sys())
+		}
+		want := ResponseInfo{StatusCode: wantStatus, ContentType: "application/json", URL: server.URL + "/new/m.info"}
+		if *resp != want {
+			t.Errorf("got %+v, want %+v", *resp, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"cache", []Option{WithCache(NewMemoryCache())}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, err := NewHTTPFS(http.DefaultClient, server.URL, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fi, err := fsys.Stat("old/m.info")
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, fi, http.StatusOK)
+
+			wantStatus := http.StatusOK
+			for range 2 {
+				f, err := fsys.Open("old/m.info")
+				if err != nil {
+					t.Fatal(err)
+				}
+				fi, err := f.Stat()
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.Close()
+				check(t, fi, wantStatus)
+				if tt.opts != nil {
+					wantStatus = http.StatusNotModified // Served from the cache
+				}
+			}
+		})
+	}
+
+	// Directories have no ResponseInfo
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL, WithAutoIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fsys.Stat(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Sys() != nil {
+		t.Errorf("Stat(.): got Sys %v, want nil", fi.Sys())
+	}
+}
+
+func TestSeek(t *testing.T) {
+	content := make([]byte, 100<<10)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	var requests, rangeRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Range") != "" {
+			rangeRequests.Add(1)
+		}
+		switch r.URL.Path {
+		case "/ranges":
+			http.ServeContent(w, r, "ranges", time.Time{}, bytes.NewReader(content))
+		case "/noranges":
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		requests      int32
+		rangeRequests int32
+	}{
+		{"ranges", 3, 2},
+		{"noranges", 2, 0}, // Forward moves only discard
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			requests.Store(0)
+			rangeRequests.Store(0)
+
+			f, err := fsys.Open(tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			seeker := f.(io.Seeker)
+
+			check := func(offset int64, whence int, wantPos int64) {
+				t.Helper()
+				pos, err := seeker.Seek(offset, whence)
+				if err != nil || pos != wantPos {
+					t.Fatalf("Seek(%d, %d): got %d, %v, want %d", offset, whence, pos, err, wantPos)
+				}
+				b := make([]byte, 10)
+				if _, err := io.ReadFull(f, b); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(b, content[pos:pos+10]) {
+					t.Errorf("at %d: got %v, want %v", pos, b, content[pos:pos+10])
+				}
+			}
+			check(0, io.SeekStart, 0)
+			check(100, io.SeekCurrent, 110)        // Small gap: discarded
+			check(-1000, io.SeekEnd, 100<<10-1000) // Large gap: Range request if supported
+			check(50, io.SeekStart, 50)            // Backward
+
+			if got := rangeRequests.Load(); got != tc.rangeRequests {
+				t.Errorf("Range requests: got %d, want %d", got, tc.rangeRequests)
+			}
+			if got := requests.Load(); got != tc.requests {
+				t.Errorf("requests: got %d, want %d", got, tc.requests)
+			}
+
+			if _, err := seeker.Seek(-1, io.SeekStart); err == nil {
+				t.Error("Seek(-1): no error")
+			}
+			if _, err := seeker.Seek(0, io.SeekEnd); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := f.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+				t.Errorf("Read at end: got %d, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	var zipData bytes.Buffer
+	w := zip.NewWriter(&zipData)
+	for i := range 50 {
+		f, _ := w.Create(fmt.Sprintf("file%d.txt", i))
+		f.Write(bytes.Repeat([]byte{byte('a' + i%26)}, 10<<10))
+	}
+	w.Close()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/mod.zip":
+			http.ServeContent(w, r, "mod.zip", time.Time{}, bytes.NewReader(zipData.Bytes()))
+		case "/noranges.zip":
+			w.Write(zipData.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.Open("noranges.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.(io.ReaderAt); ok {
+		t.Error("noranges.zip: io.ReaderAt implemented without Accept-Ranges")
+	}
+	f.Close()
+
+	f, err = fsys.Open("mod.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		t.Fatal("mod.zip: io.ReaderAt not implemented")
+	}
+
+	requests.Store(0)
+	zr, err := zip.NewReader(ra, int64(zipData.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(zr, "file42.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, bytes.Repeat([]byte{'a' + 42%26}, 10<<10)) {
+		t.Error("file42.txt: unexpected content")
+	}
+	// Central directory and file42.txt only, not the whole archive
+	if n := requests.Load(); n > 3 {
+		t.Errorf("%d requests", n)
+	}
+
+	// Read past the end
+	buf := make([]byte, 10)
+	n, err := ra.ReadAt(buf, int64(zipData.Len()-5))
+	if n != 5 || err != io.EOF {
+		t.Errorf("ReadAt at end: got %d, %v", n, err)
+	}
+
+	// Sequential reading still works after ReadAt
+	all, err := io.ReadAll(f)
+	if err != nil || !bytes.Equal(all, zipData.Bytes()) {
+		t.Errorf("Read after ReadAt: got %d bytes, %v", len(all), err)
+	}
+
+	// ReadAt fails after Close, even from the last block fetched
+	f.Close()
+	requests.Store(0)
+	if _, err := ra.ReadAt(buf, int64(zipData.Len()-5)); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("ReadAt after Close: got %v, want fs.ErrClosed", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("ReadAt after Close: %d requests", n)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	const token = "ghp_secret"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Signature") != r.URL.Path {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(strings.Join(r.Header.Values("Accept"), ",")))
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL,
+		WithHeader("Authorization", "Bearer "+token),
+		WithHeader("Accept", "text/plain"),
+		WithHeader("accept", "application/json"),
+		WithRequestHook(func(r *http.Request) {
+			r.Header.Set("X-Signature", r.URL.Path)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fsys, "private/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "text/plain,application/json" {
+		t.Errorf("Accept: got %q", b)
+	}
+
+	// Without the options
+	fsys, err = NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile(fsys, "private/file"); err == nil {
+		t.Error("no error without Authorization")
+	}
+}
+
+func TestOpenContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hung":
+			w.Write([]byte("start"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done() // Hung download
+		case "/slow":
+			<-r.Context().Done() // Hung response
+		}
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := fsys.OpenContext(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow: got %v, want context.DeadlineExceeded", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	f, err := fsys.OpenContext(ctx, "hung")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b := make([]byte, 5)
+	if _, err := io.ReadFull(f, b); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := f.Read(b); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read: got %v, want context.Canceled", err)
+	}
+	if _, err := f.Read(b); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read after cancel: got %v, want context.Canceled", err)
+	}
+}
+
+func TestStat(t *testing.T) {
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var methods []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/dir/file.txt":
+			http.ServeContent(w, r, "file.txt", modTime, strings.NewReader("hello world"))
+		case "/nohead.txt":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Write([]byte("no HEAD"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := fsys.Stat("dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "file.txt" || fi.Size() != 11 || !fi.ModTime().Equal(modTime) || fi.IsDir() {
+		t.Errorf("dir/file.txt: got %v", fs.FormatFileInfo(fi))
+	}
+
+	fi, err = fsys.Stat("nohead.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 7 {
+		t.Errorf("nohead.txt: got size %d", fi.Size())
+	}
+
+	if _, err := fsys.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing: got %v, want fs.ErrNotExist", err)
+	}
+
+	want := []string{"HEAD /dir/file.txt", "HEAD /nohead.txt", "GET /nohead.txt", "HEAD /missing"}
+	if !slices.Equal(methods, want) {
+		t.Errorf("requests: got %q, want %q", methods, want)
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modtime":
+			w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+		case "/invalid":
+			w.Header().Set("Last-Modified", "yesterday")
+		}
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]time.Time{
+		"modtime": modTime,
+		"invalid": {},
+		"none":    {},
+	} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi, err := f.Stat()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.ModTime().Equal(want) {
+			t.Errorf("%s: got %v, want %v", name, fi.ModTime(), want)
+		}
+	}
+}
+
+func TestStatNested(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/mod/@v/v1.0.0.info" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"Version":"v1.0.0"}`))
+	}))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const name = "example.com/mod/@v/v1.0.0.info"
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "v1.0.0.info" {
+		t.Errorf("Stat: Name() = %q, want %q", fi.Name(), "v1.0.0.info")
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err = f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "v1.0.0.info" {
+		t.Errorf("File.Stat: Name() = %q, want %q", fi.Name(), "v1.0.0.info")
+	}
+}
+
+func TestContentEncoding(t *testing.T) {
+	const content = `{"Version":"v1.0.0","Time":"2024-01-01T00:00:00Z"}`
+
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		// RFC 9110: deflate is the zlib format
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		// Raw deflate, sent by some servers
+		"raw-deflate": func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		},
+	}
+
+	for name, encoder := range encoders {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var buf bytes.Buffer
+				enc := encoder(&buf)
+				io.WriteString(enc, content)
+				enc.Close()
+				w.Header().Set("Content-Encoding", strings.TrimPrefix(name, "raw-"))
+				w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Write(buf.Bytes())
+			}))
+			defer server.Close()
+
+			// A transport that doesn't request compression doesn't decompress transparently
+			client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			fsys, err := NewHTTPFS(client, server.URL, WithoutRetry())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := fsys.Open("v1.0.0.info")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, ok := f.(io.ReaderAt); ok {
+				t.Error("ReaderAt on encoded content")
+			}
+			fi, err := f.Stat()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() != -1 {
+				t.Errorf("Size() = %d, want -1", fi.Size())
+			}
+			b, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != content {
+				t.Errorf("got %q, want %q", b, content)
+			}
+
+			fi, err = fsys.Stat("v1.0.0.info")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() != -1 {
+				t.Errorf("Stat: Size() = %d, want -1", fi.Size())
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package httpfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how transient HTTP failures are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles on each following retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, including delays requested
+	// by the server via Retry-After. Zero means no cap.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the policy used by default by [HTTPFS], and by
+// [WithRetry] for zero fields.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// RetryTransport is an [http.RoundTripper] that retries idempotent requests
+// (GET and HEAD) on network errors and on transient HTTP statuses
+// (429 Too Many Requests, 500, 502, 503, 504).
+//
+// The Retry-After header sent with 429 and 503 responses is honored.
+type RetryTransport struct {
+	// Base is the wrapped transport. If nil, http.DefaultTransport is used.
+	Base   http.RoundTripper
+	Policy RetryPolicy
+}
+
+// NewRetryTransport wraps base with the retry policy p.
+func NewRetryTransport(base http.RoundTripper, p RetryPolicy) *RetryTransport {
+	return &RetryTransport{Base: base, Policy: p}
+}
+
+func (t *RetryTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Policy.MaxAttempts < 2 || (req.Method != http.MethodGet && req.Method != http.MethodHead) || (req.Body != nil && req.Body != http.NoBody) {
+		return t.base().RoundTrip(req)
+	}
+
+	delay := t.Policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base().RoundTrip(req)
+		if attempt >= t.Policy.MaxAttempts || !retryable(resp, err) {
+			return resp, err
+		}
+
+		wait := delay
+		if resp != nil {
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+			}
+			// Drain (a bit) to allow connection reuse
+			io.CopyN(io.Discard, resp.Body, 4096)
+			resp.Body.Close()
+		}
+		if t.Policy.MaxDelay > 0 && wait > t.Policy.MaxDelay {
+			wait = t.Policy.MaxDelay
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// Don't retry if the request was cancelled
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header of 429 and 503 responses.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,152 @@
+package httpfs
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyHandler fails the first n requests with the given status.
+func flakyHandler(n int32, status int, hits *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= n {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestRetryTransport(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	tests := []struct {
+		name     string
+		failures int32
+		status   int
+		method   string
+		wantCode int
+		wantHits int32
+	}{
+		{"success", 0, 0, http.MethodGet, http.StatusOK, 1},
+		{"retry 503", 2, http.StatusServiceUnavailable, http.MethodGet, http.StatusOK, 3},
+		{"retry 429", 1, http.StatusTooManyRequests, http.MethodHead, http.StatusOK, 2},
+		{"give up", 5, http.StatusBadGateway, http.MethodGet, http.StatusBadGateway, 3},
+		{"not retryable", 1, http.StatusForbidden, http.MethodGet, http.StatusForbidden, 1},
+		{"not idempotent", 1, http.StatusServiceUnavailable, http.MethodPost, http.StatusServiceUnavailable, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(flakyHandler(tt.failures, tt.status, &hits))
+			defer server.Close()
+
+			client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+			var body io.Reader
+			if tt.method == http.MethodPost {
+				body = strings.NewReader("data")
+			}
+			req, _ := http.NewRequest(tt.method, server.URL, body)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRetryTransportRetryAfter(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// MaxDelay caps the delay requested by the server
+	client := &http.Client{Transport: NewRetryTransport(nil, RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    50 * time.Millisecond,
+	})}
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Retry-After not honored: retried after %v", elapsed)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(flakyHandler(1, http.StatusInternalServerError, &hits))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL, WithRetry(RetryPolicy{BaseDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if http.DefaultClient.Transport != nil {
+		t.Fatal("WithRetry modified the client")
+	}
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b, _ := io.ReadAll(f)
+	if string(b) != "ok" {
+		t.Errorf("got %q, want %q", b, "ok")
+	}
+}
+
+func TestDefaultRetry(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(flakyHandler(1, http.StatusBadGateway, &hits))
+	defer server.Close()
+
+	fsys, err := NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fsys, "file.txt")
+	if err != nil || string(b) != "ok" {
+		t.Errorf("got %q, %v", b, err)
+	}
+
+	// Fail fast
+	hits.Store(0)
+	fsys, err = NewHTTPFS(http.DefaultClient, server.URL, WithoutRetry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile(fsys, "file.txt"); err == nil {
+		t.Error("WithoutRetry: no error")
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("WithoutRetry: %d requests", n)
+	}
+}
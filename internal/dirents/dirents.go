@@ -0,0 +1,29 @@
+// Package dirents implements the ReadDir method of [io/fs.ReadDirFile] for
+// directories whose listing is loaded in memory.
+package dirents
+
+import (
+	"io"
+	"io/fs"
+)
+
+// Entries are the entries of a directory not read yet.
+type Entries []fs.DirEntry
+
+// ReadDir returns the next n entries, and consumes them. If n <= 0, all the
+// remaining entries are returned. If n > 0 and no entries remain, io.EOF is
+// returned. See [fs.ReadDirFile].
+func (e *Entries) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := *e
+		*e = nil
+		return entries, nil
+	}
+	if len(*e) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(*e))
+	entries := (*e)[:n]
+	*e = (*e)[n:]
+	return entries, nil
+}
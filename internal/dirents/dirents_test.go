@@ -0,0 +1,38 @@
+package dirents
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadDir(t *testing.T) {
+	entries, err := fs.ReadDir(fstest.MapFS{
+		"a": &fstest.MapFile{},
+		"b": &fstest.MapFile{},
+		"c": &fstest.MapFile{},
+	}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := Entries(entries)
+	if got, err := e.ReadDir(2); err != nil || len(got) != 2 || got[0].Name() != "a" {
+		t.Fatalf("ReadDir(2): got %v, %v", got, err)
+	}
+	if got, err := e.ReadDir(2); err != nil || len(got) != 1 || got[0].Name() != "c" {
+		t.Fatalf("ReadDir(2): got %v, %v", got, err)
+	}
+	if got, err := e.ReadDir(1); err != io.EOF || len(got) != 0 {
+		t.Fatalf("ReadDir(1): got %v, %v, want io.EOF", got, err)
+	}
+	if got, err := e.ReadDir(-1); err != nil || len(got) != 0 {
+		t.Fatalf("ReadDir(-1): got %v, %v", got, err)
+	}
+
+	e = Entries(entries)
+	if got, err := e.ReadDir(0); err != nil || len(got) != 3 {
+		t.Fatalf("ReadDir(0): got %v, %v", got, err)
+	}
+}
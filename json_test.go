@@ -0,0 +1,57 @@
+package modfs
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONFileClose(t *testing.T) {
+	errClose := errors.New("close failed")
+
+	for _, tc := range []struct {
+		name     string
+		data     string
+		closeErr error
+		want     []error
+	}{
+		{"ok", `{}`, nil, nil},
+		{"ok/trailing-space", "{}\n \n", nil, nil},
+		{"ok/trailing-crlf-tab", "{}\r\n\t", nil, nil},
+		{"more", `{}{}`, nil, []error{errMoreData}},
+		{"more/space", "{}\n\"\"\n", nil, []error{errMoreData}},
+		{"more/closing-brace", "{}\n}", nil, []error{errMoreData}},
+		{"more/closing-bracket", "{}]", nil, []error{errMoreData}},
+		{"more/garbage", "{} x", nil, []error{errMoreData}},
+		{"close", `{}`, errClose, []error{errClose}},
+		{"more+close", `{} []`, errClose, []error{errMoreData, errClose}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			closed := false
+			jf := &jsonFile{
+				decoder: json.NewDecoder(strings.NewReader(tc.data)),
+				close: func() error {
+					closed = true
+					return tc.closeErr
+				},
+			}
+			var v any
+			if err := jf.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			err := jf.Close()
+			if !closed {
+				t.Error("underlying file not closed")
+			}
+			if tc.want == nil && err != nil {
+				t.Errorf("got %v, want nil", err)
+			}
+			for _, want := range tc.want {
+				if !errors.Is(err, want) {
+					t.Errorf("got %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package modfs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// IsLicenseFile reports whether the base name of a file matches a common
+// license file name: LICENSE, LICENCE or COPYING (case insensitive),
+// optionally followed by an extension or a suffix ("LICENSE.md", "COPYING-GPL").
+// Go source files are excluded.
+func IsLicenseFile(name string) bool {
+	name = strings.ToUpper(path.Base(name))
+	if strings.HasSuffix(name, ".GO") {
+		return false
+	}
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return rest == "" || strings.ContainsRune(".-_", rune(rest[0]))
+		}
+	}
+	return false
+}
+
+// Licenses returns the content of the license files of the module, keyed by path.
+// License files are matched with [IsLicenseFile].
+func (ver *Version) Licenses() (map[string][]byte, error) {
+	return ver.LicensesFunc(IsLicenseFile)
+}
+
+// LicensesFunc is like [Version.Licenses] but uses match to select files.
+// match receives the path of each regular file of the module.
+func (ver *Version) LicensesFunc(match func(name string) bool) (map[string][]byte, error) {
+	vfs, err := ver.OpenFS()
+	if err != nil {
+		return nil, err
+	}
+	defer vfs.Close()
+
+	licenses := make(map[string][]byte)
+	err = fs.WalkDir(vfs, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !match(p) {
+			return nil
+		}
+		b, err := vfs.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		licenses[p] = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return licenses, nil
+}
@@ -0,0 +1,75 @@
+package modfs_test
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestIsLicenseFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"LICENSE":          true,
+		"LICENSE.md":       true,
+		"License.txt":      true,
+		"LICENCE":          true,
+		"COPYING":          true,
+		"COPYING-GPL":      true,
+		"sub/LICENSE_MIT":  true,
+		"LICENSES":         false,
+		"license.go":       false,
+		"licenser.go":      false,
+		"README.md":        false,
+		"UNLICENSE":        false,
+		"LICENSE/file.txt": false,
+	} {
+		if got := modfs.IsLicenseFile(name); got != want {
+			t.Errorf("IsLicenseFile(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestVersionLicenses(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/lic", "v1.0.0", map[string]string{
+		"go.mod":            "module example.com/lic\n",
+		"LICENSE":           "Apache License",
+		"lic.go":            "package lic\n",
+		"third/LICENSE.txt": "MIT License",
+		"third/third.go":    "package third\n",
+	})
+
+	mod, err := modfs.New(proxy).OpenModule("example.com/lic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver, err := mod.VersionLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	licenses, err := ver.Licenses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := slices.Sorted(maps.Keys(licenses)), []string{"LICENSE", "third/LICENSE.txt"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if string(licenses["third/LICENSE.txt"]) != "MIT License" {
+		t.Errorf("third/LICENSE.txt: got %q", licenses["third/LICENSE.txt"])
+	}
+
+	// Custom matcher
+	licenses, err = ver.LicensesFunc(func(name string) bool {
+		return strings.HasPrefix(name, "third/")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := slices.Sorted(maps.Keys(licenses)), []string{"third/LICENSE.txt", "third/third.go"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
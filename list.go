@@ -0,0 +1,55 @@
+package modfs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+
+	"golang.org/x/mod/module"
+)
+
+// ListModulesUnder returns the sorted paths of the modules served by the
+// proxy under the path prefix (the module at prefix included), by walking
+// the directories of the backing FS: a module is a directory that contains
+// an @v directory. An empty prefix lists all modules.
+//
+// This works only with backing FS that can list directories, like a local
+// module cache (GOMODCACHE/cache/download) or a [testing/fstest.MapFS]. Otherwise
+// an error is returned.
+func (m *ModFS) ListModulesUnder(prefix string) ([]string, error) {
+	root := "."
+	if prefix != "" {
+		var err error
+		if root, err = escapePath(prefix); err != nil {
+			return nil, err
+		}
+	}
+
+	var modules []string
+	err := fs.WalkDir(m.fs, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p == "sumdb" {
+			return fs.SkipDir
+		}
+		if d.Name() == "@v" {
+			modPath, err := module.UnescapePath(path.Dir(p))
+			if err != nil {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			modules = append(modules, modPath)
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(modules)
+	return modules, nil
+}
@@ -0,0 +1,64 @@
+package modfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/modfstest"
+)
+
+func TestListModulesUnder(t *testing.T) {
+	files := fstest.MapFS{"go.mod": {Data: []byte("module x\n")}}
+	proxy, err := modfstest.NewMapProxy(map[string]fstest.MapFS{
+		"github.com/Azure/azure-sdk-for-go@v1.0.0":    files,
+		"github.com/Azure/azure-sdk-for-go/v2@v2.0.0": files,
+		"github.com/Azure/go-autorest@v1.0.0":         files,
+		"github.com/dolmen-go/modfs@v0.1.0":           files,
+		"golang.org/x/mod@v0.28.0":                    files,
+		"golang.org/x/modules@v0.1.0":                 files,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy.MapFS["sumdb/sum.golang.org/supported"] = &fstest.MapFile{}
+	m := modfs.New(proxy)
+
+	for prefix, want := range map[string][]string{
+		"": {
+			"github.com/Azure/azure-sdk-for-go",
+			"github.com/Azure/azure-sdk-for-go/v2",
+			"github.com/Azure/go-autorest",
+			"github.com/dolmen-go/modfs",
+			"golang.org/x/mod",
+			"golang.org/x/modules",
+		},
+		"github.com/Azure": {
+			"github.com/Azure/azure-sdk-for-go",
+			"github.com/Azure/azure-sdk-for-go/v2",
+			"github.com/Azure/go-autorest",
+		},
+		"golang.org/x/mod": {"golang.org/x/mod"},
+	} {
+		got, err := m.ListModulesUnder(prefix)
+		if err != nil {
+			t.Errorf("%q: %v", prefix, err)
+			continue
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%q: got %q, want %q", prefix, got, want)
+		}
+	}
+
+	if _, err := m.ListModulesUnder("example.com/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing: got %v, want fs.ErrNotExist", err)
+	}
+
+	// An FS that can't list directories
+	if _, err := modfs.New(&streamFS{FS: proxy}).ListModulesUnder("golang.org"); err == nil {
+		t.Error("no error for an FS that can't list directories")
+	}
+}
@@ -7,21 +7,72 @@ package modfs
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
+	"mime"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
 )
 
 type ModFS struct {
 	fs fs.FS
+
+	requireLatest bool
+	probeModule   string
+	noSumDB       string // comma-separated glob patterns, as in GONOSUMDB
+	openOpts      []OpenOption
+
+	// Wrappers of fs
+	offline        bool
+	cacheDir       string
+	maxConcurrency int
+
+	mu   sync.Mutex
+	caps *Capabilities // cached result of Capabilities
+
+	ctx    context.Context // cancelled by Close
+	cancel context.CancelCauseFunc
+	tmpMu  sync.Mutex
+	tmp    map[*os.File]struct{} // temporary files, removed by Close
 }
 
-func New(f fs.FS) *ModFS {
-	return &ModFS{fs: f}
+// Option configures a [ModFS].
+type Option func(*ModFS)
+
+// RequireLatest makes [ModFS.OpenModule] fail if the proxy doesn't serve
+// the @latest endpoint for the module.
+//
+// By default, as the @latest endpoint is optional in the GOPROXY protocol,
+// a missing @latest is tolerated if the module exists (its @v/list is served,
+// even empty): [Module.Latest] is left empty.
+func RequireLatest() Option {
+	return func(m *ModFS) {
+		m.requireLatest = true
+	}
+}
+
+func New(f fs.FS, opts ...Option) *ModFS {
+	m := &ModFS{fs: f, tmp: make(map[*os.File]struct{})}
+	m.ctx, m.cancel = context.WithCancelCause(context.Background())
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.wrapFS()
+	return m
 }
 
 type (
@@ -33,16 +84,24 @@ type (
 	}
 )
 
+var errMoreData = errors.New("more data than expected")
+
+// Close closes the underlying file. Trailing data is reported together with
+// the error of the underlying close, if any. Trailing whitespace (such as
+// the final newline appended by some proxies) is not an error, but any
+// other character is, including a closing '}' or ']' that
+// [json.Decoder.More] would ignore.
 func (jf *jsonFile) Close() error {
-	if jf.decoder.More() {
-		defer jf.close()
-		return fmt.Errorf("more data than expected")
+	_, errTrailing := jf.decoder.Token() // io.EOF if only whitespace is left
+	err := jf.close()
+	if errTrailing != io.EOF {
+		err = errors.Join(errMoreData, err)
 	}
-	return jf.close()
+	return err
 }
 
-func (m *ModFS) openJSON(path string) (*jsonFile, error) {
-	f, err := m.fs.Open(path)
+func (m *ModFS) openJSON(ctx context.Context, path string) (*jsonFile, error) {
+	f, err := m.open(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", path, err)
 	}
@@ -59,8 +118,8 @@ func (m *ModFS) openJSON(path string) (*jsonFile, error) {
 	}, nil
 }
 
-func (m *ModFS) decodeJSON(path string, v any) error {
-	f, err := m.openJSON(path)
+func (m *ModFS) decodeJSON(ctx context.Context, path string, v any) error {
+	f, err := m.openJSON(ctx, path)
 	if err != nil {
 		return err
 	}
@@ -74,126 +133,581 @@ func (m *ModFS) decodeJSON(path string, v any) error {
 	return err
 }
 
-func (m *ModFS) OpenModule(path string) (*Module, error) {
+// escapePath returns the module path as served by the proxy: uppercase
+// letters are replaced by "!" followed by the lowercase letter, as in
+// "github.com/!azure/azure-sdk-for-go".
+//
+// See [module.EscapePath].
+func escapePath(path string) (string, error) {
 	if !fs.ValidPath(path) {
-		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrInvalid}
+		return "", &fs.PathError{Op: "open", Path: path, Err: fs.ErrInvalid}
 	}
-	mod := Module{fs: m, Path: path}
-	err := mod.decodeJSON("@latest", &mod.Latest)
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return "", &fs.PathError{Op: "open", Path: path, Err: fmt.Errorf("%w: %w", fs.ErrInvalid, err)}
+	}
+	return escPath, nil
+}
+
+func (m *ModFS) OpenModule(path string) (*Module, error) {
+	return m.OpenModuleContext(context.Background(), path)
+}
+
+// OpenModuleContext is like [ModFS.OpenModule] with a context.
+func (m *ModFS) OpenModuleContext(ctx context.Context, path string) (*Module, error) {
+	escPath, err := escapePath(path)
 	if err != nil {
 		return nil, err
 	}
+	mod := Module{fs: m, escPath: escPath, Path: path}
+	err = mod.decodeJSON(ctx, "@latest", &mod.Latest)
+	if err != nil {
+		if m.requireLatest || !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		// Some proxies don't serve @latest for modules that only have
+		// pseudo-versions. Check that the module exists via @v/list.
+		f, errList := m.open(ctx, mod.file("@v/list"))
+		if errList != nil {
+			return nil, err
+		}
+		f.Close()
+		mod.Latest = VersionInfo{}
+	}
 	return &mod, nil
 }
 
 type Module struct {
-	fs     *ModFS
-	Path   string
+	fs      *ModFS
+	escPath string // Path escaped for the proxy
+	Path    string
+	// Latest is the version served by the @latest endpoint of the proxy when
+	// the module was opened. It is empty if the proxy doesn't serve @latest
+	// for the module (see [RequireLatest]). See [Module.VersionLatest].
 	Latest VersionInfo
 }
 
-func (m *Module) openJSON(path string) (*jsonFile, error) {
-	return m.fs.openJSON(m.Path + "/" + path)
+// HasLatest reports whether the proxy provided a @latest version for the module.
+func (m *Module) HasLatest() bool {
+	return m.Latest.Version != ""
 }
 
-func (m *Module) decodeJSON(path string, v any) error {
-	return m.fs.decodeJSON(m.Path+"/"+path, v)
-}
+// ErrUnknownRepoRoot is returned by [Module.RepoRoot] when the proxy doesn't
+// provide the origin of the module.
+var ErrUnknownRepoRoot = errors.New("unknown repository root")
 
-func (m *Module) ListVersions() ([]*VersionInfo, error) {
-	dec, err := m.openJSON("@v/list")
-	if err != nil {
-		return nil, err
+// RepoRoot returns the URL of the VCS repository of the module, as reported
+// in the Origin of the @latest version.
+//
+// For a module hosted in a subdirectory of a repository (monorepo), the
+// subdirectory is reported by [Origin].Subdir.
+func (m *Module) RepoRoot() (string, error) {
+	if m.Latest.Origin == nil || m.Latest.Origin.URL == "" {
+		return "", fmt.Errorf("%s: %w", m.Path, ErrUnknownRepoRoot)
 	}
-	defer dec.Close()
+	return m.Latest.Origin.URL, nil
+}
 
-	var versions []*VersionInfo
-	for dec.More() {
-		var v VersionInfo
-		if err = dec.Decode(&v); err != nil {
-			return nil, err // TODO: give more context
-		}
-	}
-	return versions, nil
+// file returns the path in the proxy of the file name of the module.
+func (m *Module) file(name string) string {
+	return m.escPath + "/" + name
+}
+
+func (m *Module) openJSON(ctx context.Context, path string) (*jsonFile, error) {
+	return m.fs.openJSON(ctx, m.file(path))
 }
 
+func (m *Module) decodeJSON(ctx context.Context, path string, v any) error {
+	return m.fs.decodeJSON(ctx, m.file(path), v)
+}
+
+// ErrBuildMetadata is returned by [Module.Version] for versions with build
+// metadata other than "+incompatible", which the module system doesn't support.
+var ErrBuildMetadata = errors.New("build metadata not supported")
+
+// Version returns the version v of the module.
+//
+// Build metadata is not supported by the module system, except the
+// "+incompatible" suffix of major versions above v1 of modules without a
+// go.mod file: "v2.0.0+incompatible" is valid but "v1.2.3+meta" is rejected
+// with [ErrBuildMetadata].
 func (m *Module) Version(v string) (*Version, error) {
-	if strings.ContainsAny(v, "/\\ \t\r\n\000") {
-		return nil, fmt.Errorf("%s: invalid version %q", m.Path, v)
+	return m.VersionContext(context.Background(), v)
+}
+
+// VersionContext is like [Module.Version] with a context.
+func (m *Module) VersionContext(ctx context.Context, v string) (*Version, error) {
+	escVersion, err := m.escapeVersion(v)
+	if err != nil {
+		return nil, err
 	}
 
 	if v == m.Latest.Version {
 		return &Version{
 			module:      m,
+			escVersion:  escVersion,
 			VersionInfo: m.Latest,
 		}, nil
 	}
 
 	ver := Version{
-		module: m,
+		module:     m,
+		escVersion: escVersion,
 	}
-	if err := m.decodeJSON("@v/"+v+".info", &ver.VersionInfo); err != nil {
+	if err := m.decodeJSON(ctx, "@v/"+escVersion+".info", &ver.VersionInfo); err != nil {
 		return nil, err
 	}
 	return &ver, nil
 }
 
+// escapeVersion checks the version v and returns it as served by the proxy.
+func (m *Module) escapeVersion(v string) (string, error) {
+	if v == "" || strings.ContainsAny(v, "/\\ \t\r\n\000") {
+		return "", fmt.Errorf("%s: invalid version %q", m.Path, v)
+	}
+	if i := strings.IndexByte(v, '+'); i >= 0 && v[i:] != "+incompatible" {
+		return "", fmt.Errorf("%s: invalid version %q: %w", m.Path, v, ErrBuildMetadata)
+	}
+	escVersion, err := module.EscapeVersion(v)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", m.Path, err)
+	}
+	return escVersion, nil
+}
+
+// RawVersionInfo returns the content of the .info file of the version v of
+// the module, as served by the proxy: it can be forwarded verbatim, or
+// decoded into a custom struct to access fields unknown to [VersionInfo].
+// The content is not validated.
+func (m *Module) RawVersionInfo(v string) ([]byte, error) {
+	escVersion, err := m.escapeVersion(v)
+	if err != nil {
+		return nil, err
+	}
+	return m.fs.readFile(context.Background(), m.file("@v/"+escVersion+".info"))
+}
+
+// VersionLatest returns the version reported by the @latest endpoint of the
+// proxy ([Module.Latest]), without fetching its .info again.
+//
+// The @latest version is chosen by the proxy with the rules of the go
+// command: the highest release, else the highest pre-release, else the
+// latest pseudo-version, excluding retracted versions. It may differ from
+// the highest version of @v/list: see [Module.LatestRelease] for a version
+// chosen from @v/list only.
+//
+// If the proxy didn't serve @latest (see [Module.HasLatest]), the error
+// wraps [fs.ErrNotExist].
 func (m *Module) VersionLatest() (*Version, error) {
+	if !m.HasLatest() {
+		return nil, fmt.Errorf("%s/@latest: %w", m.Path, fs.ErrNotExist)
+	}
 	return m.Version(m.Latest.Version)
 }
 
 type VersionInfo struct {
 	Version string
 	Time    time.Time
+	Origin  *Origin `json:",omitempty"` // Provenance of the version, if provided by the proxy
+}
+
+// Origin describes the VCS source of a module version, as reported by the proxy.
+//
+// The fields are those of the Origin reported by the go command
+// (cmd/go/internal/modfetch/codehost.Origin).
+type Origin struct {
+	VCS    string `json:",omitempty"` // "git", "hg"...
+	URL    string `json:",omitempty"` // URL of the repository
+	Subdir string `json:",omitempty"` // Subdirectory of the module in the repository
+	Ref    string `json:",omitempty"` // Tag or branch
+	Hash   string `json:",omitempty"` // Commit hash
+
+	// For versions resolved from the list of tags (@latest, @v/list):
+	TagPrefix string `json:",omitempty"` // Prefix of the tags of the module ("submod/")
+	TagSum    string `json:",omitempty"` // Hash of the list of matching tags
+	RepoSum   string `json:",omitempty"` // Hash of the whole repository state
 }
 
 type Version struct {
-	module *Module
+	module     *Module
+	escVersion string // Version escaped for the proxy
 	VersionInfo
+
+	modMu   sync.Mutex
+	modFile *modfile.File // cache of ParseMod
+}
+
+// file returns the path in the proxy of the file of the version with the
+// given extension (".mod", ".zip"...).
+func (ver *Version) file(ext string) string {
+	return ver.module.file("@v/" + ver.escVersion + ext)
+}
+
+// RawInfo returns the content of the .info file of the version, as served
+// by the proxy. See [Module.RawVersionInfo].
+//
+// The file is fetched again, even if the [VersionInfo] of ver has been
+// decoded from it.
+func (ver *Version) RawInfo() ([]byte, error) {
+	return ver.module.fs.readFile(context.Background(), ver.file(".info"))
+}
+
+// GoModOption configures [Version.GoMod].
+type GoModOption func(*goModOptions)
+
+type goModOptions struct {
+	normalizeEOL bool
+}
+
+// NormalizeLineEndings makes [Version.GoMod] convert CRLF line endings to LF.
+//
+// The "h1:" hash of go.mod recorded in go.sum is computed from the exact
+// content served by the proxy: the hash of normalized content may not match.
+func NormalizeLineEndings() GoModOption {
+	return func(o *goModOptions) {
+		o.normalizeEOL = true
+	}
+}
+
+// GoMod returns the content of go.mod, verbatim by default.
+func (ver *Version) GoMod(opts ...GoModOption) ([]byte, error) {
+	return ver.GoModContext(context.Background(), opts...)
 }
 
-// GoMod returns the content of go.mod.
-func (ver *Version) GoMod() ([]byte, error) {
-	return fs.ReadFile(ver.module.fs.fs, ver.module.Path+"/@v/"+ver.Version+".mod")
+// GoModContext is like [Version.GoMod] with a context.
+func (ver *Version) GoModContext(ctx context.Context, opts ...GoModOption) ([]byte, error) {
+	var o goModOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	data, err := ver.module.fs.readFile(ctx, ver.file(".mod"))
+	if err != nil {
+		return nil, err
+	}
+	if o.normalizeEOL {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	}
+	return data, nil
 }
 
+// OpenMod opens the go.mod file served by the proxy for streaming. This is
+// the streaming counterpart of [Version.GoMod] (without [GoModOption]).
+//
+// The size reported by Stat comes from the backing FS (Content-Length with
+// [github.com/dolmen-go/modfs/httpfs.HTTPFS]); it is -1 if unknown.
+// If the proxy doesn't serve the file, the error wraps [fs.ErrNotExist].
+func (ver *Version) OpenMod() (fs.File, error) {
+	return ver.module.fs.open(context.Background(), ver.file(".mod"))
+}
+
+// ModAndSum returns the content of go.mod and go.sum.
+//
+// go.mod is fetched from the .mod endpoint of the proxy. go.sum is read from
+// the zip archive: if the backing FS provides random access ([io.ReaderAt]),
+// only the central directory and go.sum are fetched.
+// gosum is nil if the module has no go.sum.
+func (ver *Version) ModAndSum() (gomod []byte, gosum []byte, err error) {
+	gomod, err = ver.GoMod()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	zr, closer, err := ver.openZip(context.Background(), ver.module.fs.newOpenOptions(nil))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closer.Close()
+
+	gosum, err = fs.ReadFile(zr, ver.module.Path+"@"+ver.Version+"/go.sum")
+	if errors.Is(err, fs.ErrNotExist) {
+		return gomod, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return gomod, gosum, nil
+}
+
+// ZipFS is the content of a module, as returned by [Version.OpenFS].
+//
+// Sub allows to scope into a subdirectory of the module. The FS returned
+// by Sub remains valid until the ZipFS is closed.
 type ZipFS interface {
 	fs.FS
 	fs.ReadFileFS
+	fs.SubFS
 	// The FS must be closed to free resources.
 	Close() error
 }
 
-// GoMod returns an [fs.FS] with the content of the module.
+// OpenOption configures [Version.OpenFS].
+type OpenOption func(*openOptions)
+
+type openOptions struct {
+	zipSHA256         []byte
+	verifyZipHash     bool
+	allowIrregular    bool
+	verifyContentType bool
+	err               error // invalid option
+}
+
+// newOpenOptions applies the options of m ([DefaultOpenOptions]), then opts.
+func (m *ModFS) newOpenOptions(opts []OpenOption) *openOptions {
+	var o openOptions
+	for _, opt := range slices.Concat(m.openOpts, opts) {
+		opt(&o)
+	}
+	return &o
+}
+
+// ExpectZipSHA256 makes [Version.OpenFS] check that the SHA-256 digest of the
+// raw zip archive matches the given hex-encoded digest (as output by sha256sum).
+//
+// If the archive has to be downloaded (the backing FS doesn't provide
+// random access), the digest is computed while downloading. Otherwise
+// ([io.ReaderAt], such as HTTP with Range requests), the whole archive is
+// read in a first pass to compute the digest, before the archive is opened.
+// If the digest doesn't match, OpenFS fails with an error wrapping
+// [ErrHashMismatch].
+//
+// If hexDigest is not a valid hex-encoded SHA-256 digest, OpenFS fails with
+// an error wrapping [fs.ErrInvalid], before any request to the proxy.
+//
+// Note that this is not the "h1:" hash recorded in go.sum files, which is
+// computed from the content of the files of the module.
+func ExpectZipSHA256(hexDigest string) OpenOption {
+	return func(o *openOptions) {
+		b, err := hex.DecodeString(hexDigest)
+		if err != nil || len(b) != sha256.Size {
+			o.err = fmt.Errorf("ExpectZipSHA256: %w: not a SHA-256 hex digest: %q", fs.ErrInvalid, hexDigest)
+			return
+		}
+		o.zipSHA256 = b
+	}
+}
+
+// VerifyZipHash makes [Version.OpenFS] check the "h1:" hash of the content of
+// the module (see [Version.Hash]) against the hash served by the proxy in
+// $module/@v/$version.ziphash (see [Version.ZipHash]). If the hash doesn't
+// match, OpenFS fails with an error wrapping [ErrHashMismatch].
+//
+// The content of all files is read to compute the hash.
+func VerifyZipHash() OpenOption {
+	return func(o *openOptions) {
+		o.verifyZipHash = true
+	}
+}
+
+// ErrIrregularFile is returned by [Version.OpenFS] (and the other methods
+// opening the zip archive, such as [Version.ListFiles] or [Version.Hash])
+// when the zip archive of the module contains entries which are neither
+// regular files nor directories, such as symbolic links. The go command
+// rejects such modules.
+var ErrIrregularFile = errors.New("irregular file in module zip")
+
+// AllowIrregularFiles makes [Version.OpenFS] accept modules with entries
+// which are neither regular files nor directories, instead of failing with
+// [ErrIrregularFile]. Set with [DefaultOpenOptions], it applies also to the
+// other methods opening the zip archive.
+func AllowIrregularFiles() OpenOption {
+	return func(o *openOptions) {
+		o.allowIrregular = true
+	}
+}
+
+// ErrUnexpectedContentType is returned by [Version.OpenFS] with
+// [VerifyContentType] when the zip archive is served with a content type
+// other than application/zip or application/octet-stream.
+var ErrUnexpectedContentType = errors.New("unexpected content type")
+
+// VerifyContentType makes [Version.OpenFS] check the content type of the zip
+// archive, if the backing FS reports it (files with a ContentType() string
+// method, such as from [github.com/dolmen-go/modfs/httpfs.HTTPFS]), before
+// downloading it. This allows to report a clear error when an HTML page (a
+// login page, an error page...) is served instead of the archive.
+//
+// The content type must be application/zip or application/octet-stream.
+// For files without a content type (such as from [CacheDir] or from a
+// [github.com/dolmen-go/modfs/cachefs] cache), the signature of the zip
+// archive is checked instead, once the start of the file is available.
+func VerifyContentType() OpenOption {
+	return func(o *openOptions) {
+		o.verifyContentType = true
+	}
+}
+
+// contentType returns the content type of f, if reported by the backing FS.
+func contentType(f fs.File) string {
+	if ct, ok := f.(interface{ ContentType() string }); ok {
+		return ct.ContentType()
+	}
+	return ""
+}
+
+// checkContentType checks the content type of the zip archive f.
+// Files without a content type are left to [checkZipSignature].
+func checkContentType(f fs.File) error {
+	ct := contentType(f)
+	if ct == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil || (mediaType != "application/zip" && mediaType != "application/octet-stream") {
+		return fmt.Errorf("%w %q", ErrUnexpectedContentType, ct)
+	}
+	return nil
+}
+
+// checkZipSignature checks that r starts with the signature of a local file
+// header, or of an end of central directory record (empty archive).
+func checkZipSignature(r io.ReaderAt) error {
+	var sig [4]byte
+	if _, err := r.ReadAt(sig[:], 0); err != nil {
+		return err
+	}
+	if s := string(sig[:]); s != "PK\x03\x04" && s != "PK\x05\x06" {
+		return fmt.Errorf("%w: not a zip archive (starts with %q)", ErrUnexpectedContentType, s)
+	}
+	return nil
+}
+
+// ErrEmptyZip is returned by [Version.OpenFS] when the proxy serves an
+// empty (or truncated) zip archive.
+var ErrEmptyZip = errors.New("empty zip archive")
+
+// minZipSize is the size of the smallest zip archive: just an end of central directory record.
+const minZipSize = 22
+
+// ErrHashMismatch is returned when the content of a module doesn't match
+// the expected hash.
+var ErrHashMismatch = errors.New("hash mismatch")
+
+// OpenFS returns an [fs.FS] with the content of the module.
 //
 // The FS must be closed ([io.Closer]) when done.
-func (ver *Version) OpenFS() (ZipFS, error) {
-	zipPath := ver.module.Path + "/@v/" + ver.Version + ".zip"
+func (ver *Version) OpenFS(opts ...OpenOption) (ZipFS, error) {
+	return ver.OpenFSContext(context.Background(), opts...)
+}
 
-	f, err := ver.module.fs.fs.Open(ver.module.Path + "/@v/" + ver.Version + ".zip")
+// OpenFSContext is like [Version.OpenFS] with a context. Cancelling the
+// context interrupts the download of the zip archive. The context doesn't
+// apply to the returned FS.
+func (ver *Version) OpenFSContext(ctx context.Context, opts ...OpenOption) (ZipFS, error) {
+	o := ver.module.fs.newOpenOptions(opts)
+	zr, r, err := ver.openZip(ctx, o)
 	if err != nil {
 		return nil, err
 	}
+	return ver.zipFS(zr, r, o)
+}
 
+// Zip returns the raw zip archive of the module, as served by the proxy,
+// for example to copy it to a mirror. The archive is streamed from the
+// backing FS: no temporary file is created.
+//
+// The caller is responsible for closing the returned reader.
+func (ver *Version) Zip() (io.ReadCloser, error) {
+	return ver.module.fs.open(context.Background(), ver.file(".zip"))
+}
+
+// checkRegular checks that the entries of the zip archive of the module
+// are regular files or directories, unless allowed by o.
+func (ver *Version) checkRegular(zr *zip.Reader, o *openOptions) error {
+	if o.allowIrregular {
+		return nil
+	}
+	for _, f := range zr.File {
+		if mode := f.Mode(); !mode.IsRegular() && !mode.IsDir() {
+			return fmt.Errorf("%s: %w: %s (%v)", ver.file(".zip"), ErrIrregularFile, f.Name, mode.Type())
+		}
+	}
+	return nil
+}
+
+// zipFS wraps the zip archive of the module as a [ZipFS].
+// closer is closed when the ZipFS is closed (or on error).
+func (ver *Version) zipFS(zr *zip.Reader, r io.Closer, o *openOptions) (ZipFS, error) {
+	// Hide the "module@version/" prefix of all paths in the zip
+	subfs, err := fs.Sub(zr, ver.module.Path+"@"+ver.Version)
+	if err != nil {
+		r.Close()
+		return nil, &fs.PathError{Op: "zipread", Path: ver.file(".zip"), Err: err}
+	}
+
+	type ffs = interface {
+		fs.FS
+		fs.ReadFileFS
+		fs.SubFS
+	}
+	return &struct {
+		ffs
+		io.Closer
+	}{subfs.(ffs), r}, nil
+}
+
+// openZip opens the zip archive of the module, checked as required by o
+// (see [ModFS.newOpenOptions]).
+//
+// If the backing FS provides an [io.ReaderAt], only the parts of the
+// archive that are read are fetched. Else the archive is downloaded to a
+// temporary file which is removed when the returned [io.Closer] is closed.
+func (ver *Version) openZip(ctx context.Context, o *openOptions) (*zip.Reader, io.Closer, error) {
+	zipPath := ver.file(".zip")
+	if o.err != nil {
+		return nil, nil, o.err
+	}
+
+	var expectedHash string
+	if o.verifyZipHash {
+		var err error
+		if expectedHash, err = ver.zipHash(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	f, err := ver.module.fs.open(ctx, zipPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	checkSignature := false
+	if o.verifyContentType {
+		if err := checkContentType(f); err != nil {
+			f.Close()
+			return nil, nil, &fs.PathError{Op: "open", Path: zipPath, Err: err}
+		}
+		checkSignature = contentType(f) == ""
+	}
+
+	var hasher hash.Hash
+	if o.zipSHA256 != nil {
+		hasher = sha256.New()
+	}
+
+	size := int64(-1)
 	r, ok := f.(interface {
 		io.ReaderAt
 		io.Closer
 	})
 	if !ok { // not Seekable, so download the file and open the local copy
-		fi, err := os.CreateTemp("", "modfs_*.zip")
+		fi, err := ver.module.fs.createTemp()
 		if err != nil {
 			f.Close()
-			return nil, fmt.Errorf("%v: %w", zipPath, err)
+			return nil, nil, fmt.Errorf("%v: %w", zipPath, err)
 		}
-		_, err = io.Copy(fi, f)
+		var src io.Reader = &ctxReader{ctx: ctx, r: f}
+		if hasher != nil {
+			// Hash while downloading
+			src = io.TeeReader(src, hasher)
+		}
+		size, err = io.Copy(fi, src)
 		f.Close()
 		if err != nil {
-			fi.Close()
-			return nil, fmt.Errorf("%v: %w", zipPath, err)
+			ver.module.fs.removeTemp(fi)
+			return nil, nil, fmt.Errorf("%v: %w", zipPath, err)
 		}
 		if _, err = fi.Seek(0, 0); err != nil {
-			fi.Close()
-			return nil, fmt.Errorf("%v: %w", zipPath, err)
+			ver.module.fs.removeTemp(fi)
+			return nil, nil, fmt.Errorf("%v: %w", zipPath, err)
 		}
 		// Remove the temp file on Close
 		r = &struct {
@@ -202,8 +716,7 @@ func (ver *Version) OpenFS() (ZipFS, error) {
 		}{
 			ReaderAt: fi,
 			closerFunc: func() error {
-				fi.Close()
-				return os.Remove(fi.Name())
+				return ver.module.fs.removeTemp(fi)
 			},
 		}
 	}
@@ -211,34 +724,60 @@ func (ver *Version) OpenFS() (ZipFS, error) {
 	fi, err := f.Stat()
 	if err != nil {
 		r.Close()
-		return nil, &fs.PathError{Op: "stat", Path: zipPath, Err: err}
+		return nil, nil, &fs.PathError{Op: "stat", Path: zipPath, Err: err}
 	}
 	if fi.IsDir() {
 		r.Close()
-		return nil, &fs.PathError{Op: "open", Path: zipPath, Err: fs.ErrInvalid}
+		return nil, nil, &fs.PathError{Op: "open", Path: zipPath, Err: fs.ErrInvalid}
 	}
-
-	zr, err := zip.NewReader(r, fi.Size())
-	if err != nil {
+	if size < 0 {
+		size = fi.Size()
+	}
+	if size < minZipSize {
 		r.Close()
-		return nil, err
+		return nil, nil, fmt.Errorf("%s@%s: %w (%d bytes)", ver.module.Path, ver.Version, ErrEmptyZip, size)
+	}
+	if checkSignature {
+		if err := checkZipSignature(r); err != nil {
+			r.Close()
+			return nil, nil, &fs.PathError{Op: "open", Path: zipPath, Err: err}
+		}
 	}
 
-	// Hide the "module@version/" prefix of all paths in the zip
-	subfs, err := fs.Sub(zr, ver.module.Path+"@"+ver.Version)
+	if hasher != nil {
+		if ok { // Not downloaded: hash the content now
+			if _, err := io.Copy(hasher, io.NewSectionReader(r, 0, size)); err != nil {
+				r.Close()
+				return nil, nil, &fs.PathError{Op: "read", Path: zipPath, Err: err}
+			}
+		}
+		if sum := hasher.Sum(nil); !bytes.Equal(sum, o.zipSHA256) {
+			r.Close()
+			return nil, nil, fmt.Errorf("%s: %w: got sha256 %x", zipPath, ErrHashMismatch, sum)
+		}
+	}
+
+	zr, err := zip.NewReader(r, size)
 	if err != nil {
 		r.Close()
-		return nil, &fs.PathError{Op: "zipread", Path: zipPath, Err: err}
+		return nil, nil, err
+	}
+	if err := ver.checkRegular(zr, o); err != nil {
+		r.Close()
+		return nil, nil, err
 	}
 
-	type ffs = interface {
-		fs.FS
-		fs.ReadFileFS
+	if o.verifyZipHash {
+		h, err := ver.hashZip(zr)
+		if err == nil {
+			err = ver.checkHash(h, expectedHash)
+		}
+		if err != nil {
+			r.Close()
+			return nil, nil, err
+		}
 	}
-	return &struct {
-		ffs
-		io.Closer
-	}{subfs.(ffs), r}, nil
+	return zr, r, nil
 }
 
 type closerFunc func() error
@@ -0,0 +1,160 @@
+// Package modfstest provides helpers to test code using [github.com/dolmen-go/modfs].
+package modfstest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// MapProxy is an in-memory GOPROXY, to be used as the backend of [github.com/dolmen-go/modfs.New].
+//
+// The embedded [fstest.MapFS] holds the files served by the proxy:
+// $module/@latest, $module/@v/list, $module/@v/$version.info, .mod and .zip.
+type MapProxy struct {
+	fstest.MapFS
+}
+
+// NewMapProxy builds a proxy serving the given module versions.
+//
+// The keys of modules are "path@version". The values are the files of the
+// module at that version. If go.mod is absent, the proxy serves a go.mod
+// with just a module directive, as proxy.golang.org does.
+// The time of a version is the most recent ModTime of its files.
+func NewMapProxy(modules map[string]fstest.MapFS) (*MapProxy, error) {
+	p := &MapProxy{MapFS: make(fstest.MapFS)}
+	versions := make(map[string][]string)
+	for _, key := range slices.Sorted(maps.Keys(modules)) {
+		path, version, ok := strings.Cut(key, "@")
+		if !ok {
+			return nil, fmt.Errorf("%q: module@version expected", key)
+		}
+		if err := p.add(path, version, modules[key]); err != nil {
+			return nil, err
+		}
+		versions[path] = append(versions[path], version)
+	}
+
+	for path, vers := range versions {
+		escPath, _ := module.EscapePath(path) // already checked by add
+		semver.Sort(vers)
+
+		var list []byte
+		for _, v := range vers {
+			if !module.IsPseudoVersion(v) {
+				list = append(list, v+"\n"...)
+			}
+		}
+		p.MapFS[escPath+"/@v/list"] = &fstest.MapFile{Data: list}
+
+		latest := latestVersion(vers)
+		escVersion, _ := module.EscapeVersion(latest)
+		p.MapFS[escPath+"/@latest"] = p.MapFS[escPath+"/@v/"+escVersion+".info"]
+	}
+	return p, nil
+}
+
+// latestVersion returns the highest release version, or the highest
+// pre-release if there is no release, or else the highest pseudo-version.
+// vers must be sorted.
+func latestVersion(vers []string) string {
+	var prerelease string
+	for _, v := range slices.Backward(vers) {
+		if module.IsPseudoVersion(v) {
+			continue
+		}
+		if semver.Prerelease(v) == "" {
+			return v
+		}
+		if prerelease == "" {
+			prerelease = v
+		}
+	}
+	if prerelease != "" {
+		return prerelease
+	}
+	return vers[len(vers)-1]
+}
+
+func (p *MapProxy) add(path, version string, files fstest.MapFS) error {
+	if err := module.Check(path, version); err != nil {
+		return err
+	}
+	escPath, _ := module.EscapePath(path)
+	escVersion, _ := module.EscapeVersion(version)
+
+	zipData, err := Zip(path, version, files)
+	if err != nil {
+		return err
+	}
+
+	var modTime time.Time
+	for _, f := range files {
+		if f.ModTime.After(modTime) {
+			modTime = f.ModTime
+		}
+	}
+	info, err := json.Marshal(struct {
+		Version string
+		Time    time.Time
+	}{version, modTime.UTC()})
+	if err != nil {
+		return err
+	}
+
+	goMod := []byte("module " + path + "\n")
+	if f, ok := files["go.mod"]; ok {
+		goMod = f.Data
+	}
+
+	prefix := escPath + "/@v/" + escVersion
+	p.MapFS[prefix+".info"] = &fstest.MapFile{Data: info, ModTime: modTime}
+	p.MapFS[prefix+".mod"] = &fstest.MapFile{Data: goMod, ModTime: modTime}
+	p.MapFS[prefix+".zip"] = &fstest.MapFile{Data: zipData, ModTime: modTime}
+	return nil
+}
+
+// Zip builds a module zip archive, where the paths of files are prefixed
+// with "path@version/". Directories of files are ignored.
+func Zip(path, version string, files fstest.MapFS) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		f := files[name]
+		if f.Mode&fs.ModeDir != 0 {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("%s@%s: invalid file path %q", path, version, name)
+		}
+		hdr := &zip.FileHeader{
+			Name:     path + "@" + version + "/" + name,
+			Method:   zip.Deflate,
+			Modified: f.ModTime,
+		}
+		if f.Mode != 0 {
+			hdr.SetMode(f.Mode)
+		}
+		zf, err := w.CreateHeader(hdr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zf.Write(f.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package modfstest_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/modfstest"
+)
+
+func ExampleMapProxy() {
+	proxy, err := modfstest.NewMapProxy(map[string]fstest.MapFS{
+		"example.com/hello@v1.0.0": {
+			"go.mod":   {Data: []byte("module example.com/hello\n\ngo 1.22\n")},
+			"hello.go": {Data: []byte("package hello\n")},
+		},
+		"example.com/hello@v1.1.0": {
+			"go.mod":   {Data: []byte("module example.com/hello\n\ngo 1.24\n")},
+			"hello.go": {Data: []byte("package hello\n\nconst Hello = \"world\"\n")},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mod, err := modfs.New(proxy).OpenModule("example.com/hello")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("latest:", mod.Latest.Version)
+
+	ver, err := mod.Version("v1.0.0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	vfs, err := ver.OpenFS()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer vfs.Close()
+	src, err := vfs.ReadFile("hello.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s", src)
+
+	// Output:
+	// latest: v1.1.0
+	// package hello
+}
+
+func TestMapProxy(t *testing.T) {
+	proxy, err := modfstest.NewMapProxy(map[string]fstest.MapFS{
+		"github.com/Upper/mod@v1.0.0":                             {"a.go": {Data: []byte("package mod\n")}},
+		"github.com/Upper/mod@v1.1.0-rc.1":                        {},
+		"github.com/Upper/mod@v0.0.0-20250101000000-abcdefabcdef": {},
+		"example.com/pre@v0.1.0-alpha":                            {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"github.com/!upper/mod/@v/list":        "v1.0.0\nv1.1.0-rc.1\n",
+		"github.com/!upper/mod/@v/v1.0.0.mod":  "module github.com/Upper/mod\n",
+		"github.com/!upper/mod/@latest":        `{"Version":"v1.0.0","Time":"0001-01-01T00:00:00Z"}`,
+		"example.com/pre/@latest":              `{"Version":"v0.1.0-alpha","Time":"0001-01-01T00:00:00Z"}`,
+		"example.com/pre/@v/v0.1.0-alpha.info": `{"Version":"v0.1.0-alpha","Time":"0001-01-01T00:00:00Z"}`,
+	} {
+		got, err := fs.ReadFile(proxy, name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+
+	data, err := fs.ReadFile(proxy, "github.com/!upper/mod/@v/v1.0.0.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, "github.com/Upper/mod@v1.0.0/") {
+			t.Errorf("unexpected zip entry %q", f.Name)
+		}
+	}
+
+	if _, err := modfstest.NewMapProxy(map[string]fstest.MapFS{"example.com/bad": {}}); err == nil {
+		t.Error("missing version not detected")
+	}
+}
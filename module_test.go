@@ -0,0 +1,383 @@
+package modfs_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/httpfs"
+	"github.com/dolmen-go/modfs/modfstest"
+)
+
+func TestOpenModuleNoLatest(t *testing.T) {
+	proxy := fstest.MapFS{
+		// Module with only pseudo-versions: empty list, no @latest
+		"example.com/pseudo/@v/list": &fstest.MapFile{},
+		// Module with a @latest
+		"example.com/tagged/@latest": &fstest.MapFile{Data: []byte(`{"Version":"v1.0.0","Time":"2025-01-01T00:00:00Z"}`)},
+		"example.com/tagged/@v/list": &fstest.MapFile{Data: []byte("v1.0.0\n")},
+	}
+
+	t.Run("tagged", func(t *testing.T) {
+		mod, err := modfs.New(proxy).OpenModule("example.com/tagged")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mod.HasLatest() {
+			t.Error("HasLatest() = false, want true")
+		}
+		if mod.Latest.Version != "v1.0.0" {
+			t.Errorf("Latest.Version = %q, want v1.0.0", mod.Latest.Version)
+		}
+		// The .info is not fetched again (the proxy doesn't serve it)
+		ver, err := mod.VersionLatest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ver.Version != "v1.0.0" || !ver.Time.Equal(mod.Latest.Time) {
+			t.Errorf("VersionLatest() = %+v, want %+v", ver.VersionInfo, mod.Latest)
+		}
+	})
+
+	t.Run("pseudo-only", func(t *testing.T) {
+		mod, err := modfs.New(proxy).OpenModule("example.com/pseudo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mod.HasLatest() {
+			t.Errorf("HasLatest() = true, want false (Latest: %+v)", mod.Latest)
+		}
+		if _, err := mod.VersionLatest(); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("VersionLatest(): got %v, want fs.ErrNotExist", err)
+		}
+	})
+
+	t.Run("pseudo-only/RequireLatest", func(t *testing.T) {
+		_, err := modfs.New(proxy, modfs.RequireLatest()).OpenModule("example.com/pseudo")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("got %v, want fs.ErrNotExist", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := modfs.New(proxy).OpenModule("example.com/missing")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("got %v, want fs.ErrNotExist", err)
+		}
+	})
+}
+
+// TestOpenModuleGone checks that the 410 Gone status, sent by
+// proxy.golang.org for missing modules and versions, is reported as
+// fs.ErrNotExist.
+func TestOpenModuleGone(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/exists", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/exists\n",
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		data, err := fs.ReadFile(proxy, name)
+		if err != nil {
+			w.WriteHeader(http.StatusGone)
+			fmt.Fprintf(w, "not found: %s", name)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	hfs, err := httpfs.NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := modfs.New(hfs)
+
+	_, err = m.OpenModule("example.com/missing")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenModule: got %v, want fs.ErrNotExist", err)
+	}
+
+	mod, err := m.OpenModule("example.com/exists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = mod.Version("v2.0.0")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Version: got %v, want fs.ErrNotExist", err)
+	}
+	var httpErr *httpfs.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusGone {
+		t.Errorf("Version: got %v, want HTTP status 410", err)
+	}
+}
+
+func TestModuleRepoRoot(t *testing.T) {
+	proxy := fstest.MapFS{
+		"example.com/mono/submod/@latest": &fstest.MapFile{Data: []byte(`{
+			"Version": "v1.0.0",
+			"Time": "2025-01-01T00:00:00Z",
+			"Origin": {
+				"VCS": "git",
+				"URL": "https://example.com/mono",
+				"Subdir": "submod",
+				"Ref": "refs/tags/submod/v1.0.0",
+				"Hash": "0123456789abcdef0123456789abcdef01234567"
+			}
+		}`)},
+		"example.com/plain/@latest": &fstest.MapFile{Data: []byte(`{"Version":"v1.0.0","Time":"2025-01-01T00:00:00Z"}`)},
+	}
+
+	mod, err := modfs.New(proxy).OpenModule("example.com/mono/submod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := mod.RepoRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != "https://example.com/mono" {
+		t.Errorf("RepoRoot: got %q", root)
+	}
+	if mod.Latest.Origin.Subdir != "submod" {
+		t.Errorf("Subdir: got %q", mod.Latest.Origin.Subdir)
+	}
+
+	mod, err = modfs.New(proxy).OpenModule("example.com/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mod.RepoRoot(); !errors.Is(err, modfs.ErrUnknownRepoRoot) {
+		t.Errorf("got %v, want ErrUnknownRepoRoot", err)
+	}
+}
+
+func TestVersionBuildMetadata(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/inc", "v2.0.0+incompatible", nil)
+	mod, err := modfs.New(proxy).OpenModule("example.com/inc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ver, err := mod.Version("v2.0.0+incompatible")
+	if err != nil {
+		t.Fatalf("+incompatible: %v", err)
+	}
+	if ver.Version != "v2.0.0+incompatible" {
+		t.Errorf("got %q", ver.Version)
+	}
+
+	for _, v := range []string{"v1.2.3+meta", "v2.0.0+incompatible.1", "v1.0.0+"} {
+		if _, err := mod.Version(v); !errors.Is(err, modfs.ErrBuildMetadata) {
+			t.Errorf("%s: got %v, want ErrBuildMetadata", v, err)
+		}
+	}
+}
+
+func TestVersionAtCommit(t *testing.T) {
+	commitTime := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	const rev = "0123456789abcdef0123456789abcdef01234567"
+
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/pseudo", "v0.0.0-20250304050607-0123456789ab", nil)
+	addModule(t, proxy, "example.com/pseudo/v2", "v2.0.0-20250304050607-0123456789ab", nil)
+
+	for path, want := range map[string]string{
+		"example.com/pseudo":    "v0.0.0-20250304050607-0123456789ab",
+		"example.com/pseudo/v2": "v2.0.0-20250304050607-0123456789ab",
+	} {
+		mod, err := modfs.New(proxy).OpenModule(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Time zone must not matter
+		ver, err := mod.VersionAtCommit(commitTime.In(time.FixedZone("X", 3600)), rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ver.Version != want {
+			t.Errorf("%s: got %q, want %q", path, ver.Version, want)
+		}
+	}
+
+	mod, err := modfs.New(proxy).OpenModule("example.com/pseudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mod.VersionAtCommit(commitTime, "fedcba9876543210"); !errors.Is(err, modfs.ErrUnknownRevision) {
+		t.Errorf("got %v, want ErrUnknownRevision", err)
+	}
+	for _, r := range []string{"", "0123", "0123456789AB", "not-a-revision"} {
+		if _, err := mod.VersionAtCommit(commitTime, r); err == nil || errors.Is(err, modfs.ErrUnknownRevision) {
+			t.Errorf("%q: got %v, want invalid revision", r, err)
+		}
+	}
+	if _, err := mod.VersionAtCommit(time.Time{}, rev); err == nil {
+		t.Error("zero time accepted")
+	}
+}
+
+// recordFS records the names of the files opened.
+type recordFS struct {
+	fs.FS
+	opened []string
+}
+
+func (r *recordFS) Open(name string) (fs.File, error) {
+	r.opened = append(r.opened, name)
+	return r.FS.Open(name)
+}
+
+func TestEscapePath(t *testing.T) {
+	const path = "github.com/Azure/azure-sdk-for-go"
+	proxy, err := modfstest.NewMapProxy(map[string]fstest.MapFS{
+		path + "@v1.0.0": {"go.mod": {Data: []byte("module " + path + "\n")}},
+		path + "@v1.1.0-RC1": {
+			"go.mod": {Data: []byte("module " + path + "\n")},
+			"sdk.go": {Data: []byte("package sdk\n")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &recordFS{FS: proxy}
+
+	mod, err := modfs.New(rec).OpenModule(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.Path != path {
+		t.Errorf("Path: got %q", mod.Path)
+	}
+	versions, err := mod.ListVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versionStrings(versions); !slices.Equal(got, []string{"v1.0.0", "v1.1.0-RC1"}) {
+		t.Errorf("ListVersions: got %q", got)
+	}
+	ver, err := mod.Version("v1.1.0-RC1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ver.GoMod(); err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := ver.OpenFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	if _, err := fsys.ReadFile("sdk.go"); err != nil {
+		t.Error(err)
+	}
+
+	want := []string{
+		"github.com/!azure/azure-sdk-for-go/@latest",
+		"github.com/!azure/azure-sdk-for-go/@v/list",
+		"github.com/!azure/azure-sdk-for-go/@v/v1.1.0-!r!c1.info",
+		"github.com/!azure/azure-sdk-for-go/@v/v1.1.0-!r!c1.mod",
+		"github.com/!azure/azure-sdk-for-go/@v/v1.1.0-!r!c1.zip",
+	}
+	if !slices.Equal(rec.opened, want) {
+		t.Errorf("opened:\ngot  %q\nwant %q", rec.opened, want)
+	}
+}
+
+func TestVersionInfoOrigin(t *testing.T) {
+	proxy := fstest.MapFS{
+		"example.com/mono/submod/@latest": &fstest.MapFile{Data: []byte(`{"Version":"v1.1.0","Time":"2025-02-01T00:00:00Z"}`)},
+		"example.com/mono/submod/@v/v1.0.0.info": &fstest.MapFile{Data: []byte(`{
+			"Version": "v1.0.0",
+			"Time": "2025-01-01T00:00:00Z",
+			"Origin": {
+				"VCS": "git",
+				"URL": "https://example.com/mono",
+				"Subdir": "submod",
+				"TagPrefix": "submod/",
+				"TagSum": "t1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+				"Ref": "refs/tags/submod/v1.0.0",
+				"Hash": "0123456789abcdef0123456789abcdef01234567"
+			}
+		}`)},
+	}
+	mod, err := modfs.New(proxy).OpenModule("example.com/mono/submod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No Origin
+	if mod.Latest.Origin != nil {
+		t.Errorf("@latest: got Origin %+v, want nil", mod.Latest.Origin)
+	}
+
+	ver, err := mod.Version("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := modfs.Origin{
+		VCS:       "git",
+		URL:       "https://example.com/mono",
+		Subdir:    "submod",
+		Ref:       "refs/tags/submod/v1.0.0",
+		Hash:      "0123456789abcdef0123456789abcdef01234567",
+		TagPrefix: "submod/",
+		TagSum:    "t1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+	}
+	if ver.Origin == nil || *ver.Origin != want {
+		t.Errorf("got Origin %+v, want %+v", ver.Origin, want)
+	}
+}
+
+func TestRawInfo(t *testing.T) {
+	const info = `{"Version":"v1.0.0","Time":"2025-01-01T00:00:00Z","Mirror":"eu-west"}`
+	proxy := fstest.MapFS{
+		"example.com/raw/@latest":           &fstest.MapFile{Data: []byte(info)},
+		"example.com/raw/@v/v1.0.0.info":    &fstest.MapFile{Data: []byte(info)},
+		"example.com/raw/@v/v2.0.0-rc.info": &fstest.MapFile{Data: []byte(`{"Version":`)},
+	}
+	mod, err := modfs.New(proxy).OpenModule("example.com/raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := mod.RawVersionInfo("v1.0.0")
+	if err != nil || string(b) != info {
+		t.Errorf("RawVersionInfo: got %q, %v", b, err)
+	}
+	var custom struct {
+		Version string
+		Mirror  string
+	}
+	if err := json.Unmarshal(b, &custom); err != nil || custom.Mirror != "eu-west" {
+		t.Errorf("custom decoding: got %+v, %v", custom, err)
+	}
+
+	ver, err := mod.VersionLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ver.RawInfo(); err != nil || string(b) != info {
+		t.Errorf("RawInfo: got %q, %v", b, err)
+	}
+
+	// Invalid JSON is returned as is
+	if b, err := mod.RawVersionInfo("v2.0.0-rc"); err != nil || string(b) != `{"Version":` {
+		t.Errorf("RawVersionInfo(v2.0.0-rc): got %q, %v", b, err)
+	}
+	if _, err := mod.RawVersionInfo("v3.0.0"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("RawVersionInfo(v3.0.0): got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := mod.RawVersionInfo("v1.0.0+meta"); !errors.Is(err, modfs.ErrBuildMetadata) {
+		t.Errorf("RawVersionInfo(v1.0.0+meta): got %v, want ErrBuildMetadata", err)
+	}
+}
@@ -0,0 +1,100 @@
+package modfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// ErrOffline is returned (wrapped with [fs.ErrNotExist]) by a [ModFS] in
+// [Offline] mode when a file is missing from the local cache.
+var ErrOffline = errors.New("not available offline")
+
+// Offline makes the [ModFS] read only local files, for air-gapped
+// environments: a file missing from the cache fails with [ErrOffline]
+// instead of being fetched.
+//
+// If the backing FS has a Local method returning the FS of its local
+// storage, like [github.com/dolmen-go/modfs/cachefs.FS], that FS is used.
+// Else the backing FS is assumed to be local (such as an [os.DirFS] of
+// GOMODCACHE/cache/download).
+//
+// As the list of versions of a module ($module/@v/list) is not always
+// stored in a cache, it is built from the versions whose .info is in the
+// cache, if missing.
+func Offline() Option {
+	return func(m *ModFS) {
+		m.offline = true
+	}
+}
+
+// offlineFS is the backing FS of a [ModFS] in [Offline] mode.
+type offlineFS struct {
+	fsys fs.FS
+}
+
+func newOfflineFS(fsys fs.FS) *offlineFS {
+	if l, ok := fsys.(interface{ Local() fs.FS }); ok {
+		fsys = l.Local()
+	}
+	return &offlineFS{fsys: fsys}
+}
+
+func (o *offlineFS) Open(name string) (fs.File, error) {
+	f, err := o.fsys.Open(name)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return f, err
+	}
+	if dir, ok := strings.CutSuffix(name, "/list"); ok && path.Base(dir) == "@v" {
+		if list, ok := o.list(dir); ok {
+			return &listFile{bytes.NewReader(list)}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("%w: %w", ErrOffline, fs.ErrNotExist)}
+}
+
+// list builds the content of the @v/list file of directory dir from the
+// .info files in dir.
+func (o *offlineFS) list(dir string) ([]byte, bool) {
+	entries, err := fs.ReadDir(o.fsys, dir)
+	if err != nil {
+		return nil, false
+	}
+	var versions []string
+	for _, e := range entries {
+		escVersion, ok := strings.CutSuffix(e.Name(), ".info")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if v, err := module.UnescapeVersion(escVersion); err == nil && semver.IsValid(v) {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, false
+	}
+	semver.Sort(versions)
+	return []byte(strings.Join(versions, "\n") + "\n"), true
+}
+
+// listFile is a @v/list built in memory by [offlineFS]. It is its own
+// [fs.FileInfo], with the Size of the [bytes.Reader].
+type listFile struct {
+	*bytes.Reader
+}
+
+func (f *listFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *listFile) Close() error               { return nil }
+
+func (f *listFile) Name() string       { return "list" }
+func (f *listFile) Mode() fs.FileMode  { return 0444 }
+func (f *listFile) ModTime() time.Time { return time.Time{} }
+func (f *listFile) IsDir() bool        { return false }
+func (f *listFile) Sys() any           { return nil }
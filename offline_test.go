@@ -0,0 +1,65 @@
+package modfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestOffline(t *testing.T) {
+	proxy := fstest.MapFS{}
+	for _, v := range []string{"v1.0.0", "v1.1.0"} {
+		addModule(t, proxy, "example.com/off", v, map[string]string{
+			"go.mod": "module example.com/off\n",
+			"off.go": "package off\n",
+		})
+	}
+	addModule(t, proxy, "example.com/other", "v1.0.0", nil)
+	cacheDir := t.TempDir()
+
+	// Fill the cache with v1.0.0
+	ver := openVersion(t, modfs.NewCachedProxy(cacheDir, proxy), "example.com/off", "v1.0.0")
+	fsys, err := ver.OpenFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys.Close()
+
+	remote := &recordFS{FS: proxy}
+	m := modfs.NewCachedProxy(cacheDir, remote, modfs.Offline())
+	mod, err := m.OpenModule("example.com/off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := mod.ListVersions()
+	if err != nil || len(versions) != 1 || versions[0].Version != "v1.0.0" {
+		t.Errorf("ListVersions: got %v, %v", versions, err)
+	}
+	ver, err = mod.Version("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err = ver.OpenFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fsys.ReadFile("off.go"); err != nil || string(b) != "package off\n" {
+		t.Errorf("off.go: got %q, %v", b, err)
+	}
+	fsys.Close()
+
+	// Not in the cache
+	if _, err := mod.Version("v1.1.0"); !errors.Is(err, modfs.ErrOffline) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("v1.1.0: got %v, want ErrOffline", err)
+	}
+	if _, err := m.OpenModule("example.com/other"); !errors.Is(err, modfs.ErrOffline) {
+		t.Errorf("example.com/other: got %v, want ErrOffline", err)
+	}
+
+	if len(remote.opened) > 0 {
+		t.Errorf("remote opened: %q", remote.opened)
+	}
+}
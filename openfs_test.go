@@ -0,0 +1,333 @@
+package modfs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/httpfs"
+	"github.com/dolmen-go/modfs/modfstest"
+)
+
+func TestOpenFSExpectZipSHA256(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/hashed", "v1.0.0", map[string]string{
+		"go.mod":    "module example.com/hashed\n",
+		"hashed.go": "package hashed\n",
+	})
+	zipData := proxy["example.com/hashed/@v/v1.0.0.zip"].Data
+	sum := sha256.Sum256(zipData)
+	goodSum := hex.EncodeToString(sum[:])
+
+	t.Run("download", func(t *testing.T) {
+		sfs := &streamFS{FS: proxy}
+		ver := openVersion(t, modfs.New(sfs), "example.com/hashed", "v1.0.0")
+		sfs.read.Store(0)
+
+		vfs, err := ver.OpenFS(modfs.ExpectZipSHA256(goodSum))
+		if err != nil {
+			t.Fatal(err)
+		}
+		vfs.Close()
+		// Single pass over the data
+		if got := sfs.read.Load(); got != int64(len(zipData)) {
+			t.Errorf("%d bytes read, want %d", got, len(zipData))
+		}
+
+		_, err = ver.OpenFS(modfs.ExpectZipSHA256(hex.EncodeToString(make([]byte, sha256.Size))))
+		if !errors.Is(err, modfs.ErrHashMismatch) {
+			t.Errorf("got %v, want ErrHashMismatch", err)
+		}
+	})
+
+	t.Run("ReaderAt", func(t *testing.T) {
+		ver := openVersion(t, modfs.New(proxy), "example.com/hashed", "v1.0.0")
+
+		vfs, err := ver.OpenFS(modfs.ExpectZipSHA256(goodSum))
+		if err != nil {
+			t.Fatal(err)
+		}
+		vfs.Close()
+
+		_, err = ver.OpenFS(modfs.ExpectZipSHA256(hex.EncodeToString(make([]byte, sha256.Size))))
+		if !errors.Is(err, modfs.ErrHashMismatch) {
+			t.Errorf("got %v, want ErrHashMismatch", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfs := &countFS{FS: proxy}
+		ver := openVersion(t, modfs.New(cfs), "example.com/hashed", "v1.0.0")
+		cfs.opens = 0
+
+		for _, digest := range []string{"invalid", goodSum[:10], goodSum + "00"} {
+			if _, err := ver.OpenFS(modfs.ExpectZipSHA256(digest)); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%q: got %v, want fs.ErrInvalid", digest, err)
+			}
+		}
+		if _, err := ver.OpenFSStreaming(modfs.ExpectZipSHA256("invalid")); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("OpenFSStreaming: got %v, want fs.ErrInvalid", err)
+		}
+		if cfs.opens != 0 {
+			t.Errorf("%d files opened, want 0", cfs.opens)
+		}
+	})
+}
+
+func TestOpenFSEmptyZip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/empty/@latest":
+			w.Write([]byte(`{"Version":"v1.0.0","Time":"2025-01-01T00:00:00Z"}`))
+		case "/example.com/empty/@v/v1.0.0.zip":
+			w.Header().Set("Content-Type", "application/zip")
+			// Empty body
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	hfs, err := httpfs.NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver := openVersion(t, modfs.New(hfs), "example.com/empty", "v1.0.0")
+	_, err = ver.OpenFS()
+	if !errors.Is(err, modfs.ErrEmptyZip) {
+		t.Fatalf("got %v, want ErrEmptyZip", err)
+	}
+	if !strings.Contains(err.Error(), "example.com/empty@v1.0.0") {
+		t.Errorf("missing module context: %v", err)
+	}
+}
+
+func TestOpenFSSub(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/sub", "v1.0.0", map[string]string{
+		"go.mod":                "module example.com/sub\n",
+		"internal/util/util.go": "package util\n",
+	})
+	ver := openVersion(t, modfs.New(proxy), "example.com/sub", "v1.0.0")
+	vfs, err := ver.OpenFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	internal, err := vfs.Sub("internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(internal, "util/util.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "package util\n" {
+		t.Errorf("got %q", b)
+	}
+	if err := vfs.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestZip(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/raw", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/raw\n",
+	})
+	sfs := &streamFS{FS: proxy}
+	ver := openVersion(t, modfs.New(sfs), "example.com/raw", "v1.0.0")
+
+	r, err := ver.Zip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, proxy["example.com/raw/@v/v1.0.0.zip"].Data) {
+		t.Error("content mismatch")
+	}
+}
+
+func TestOpenFSIrregularFile(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/link", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/link\n",
+	})
+
+	// Rebuild the zip with a symlink entry
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("example.com/link@v1.0.0/go.mod")
+	io.WriteString(w, "module example.com/link\n")
+	hdr := &zip.FileHeader{Name: "example.com/link@v1.0.0/passwd"}
+	hdr.SetMode(fs.ModeSymlink | 0o777)
+	w, _ = zw.CreateHeader(hdr)
+	io.WriteString(w, "/etc/passwd")
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	proxy["example.com/link/@v/v1.0.0.zip"] = &fstest.MapFile{Data: buf.Bytes()}
+
+	ver := openVersion(t, modfs.New(proxy), "example.com/link", "v1.0.0")
+	if _, err := ver.OpenFS(); !errors.Is(err, modfs.ErrIrregularFile) {
+		t.Errorf("got %v, want ErrIrregularFile", err)
+	}
+
+	fsys, err := ver.OpenFS(modfs.AllowIrregularFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+	fi, err := fs.Stat(fsys, "passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Type() != fs.ModeSymlink {
+		t.Errorf("passwd: got mode %v", fi.Mode())
+	}
+
+	// All the methods opening the archive check the entries
+	for name, open := range map[string]func(*modfs.Version) error{
+		"OpenFSStreaming": func(ver *modfs.Version) error {
+			fsys, err := ver.OpenFSStreaming()
+			if err == nil {
+				fsys.Close()
+			}
+			return err
+		},
+		"ListFiles": func(ver *modfs.Version) error {
+			_, err := ver.ListFiles()
+			return err
+		},
+		"Files": func(ver *modfs.Version) error {
+			seq, err := ver.Files()
+			if err != nil {
+				return err
+			}
+			for _, r := range seq {
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		"ModAndSum": func(ver *modfs.Version) error {
+			_, _, err := ver.ModAndSum()
+			return err
+		},
+		"Hash": func(ver *modfs.Version) error {
+			_, err := ver.Hash()
+			return err
+		},
+	} {
+		if err := open(ver); !errors.Is(err, modfs.ErrIrregularFile) {
+			t.Errorf("%s: got %v, want ErrIrregularFile", name, err)
+		}
+		allowed := openVersion(t, modfs.New(proxy, modfs.DefaultOpenOptions(modfs.AllowIrregularFiles())), "example.com/link", "v1.0.0")
+		if err := open(allowed); err != nil {
+			t.Errorf("%s with AllowIrregularFiles: %v", name, err)
+		}
+	}
+}
+
+func TestOpenFSContentType(t *testing.T) {
+	zipData, err := modfstest.Zip("example.com/ct", "v1.0.0", fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module example.com/ct\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/ct/@latest":
+			w.Write([]byte(`{"Version":"v1.1.0","Time":"2025-01-01T00:00:00Z"}`))
+		case "/example.com/ct/@v/v1.0.0.info":
+			w.Write([]byte(`{"Version":"v1.0.0","Time":"2025-01-01T00:00:00Z"}`))
+		case "/example.com/ct/@v/v1.0.0.zip":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Write(zipData)
+		case "/example.com/ct/@v/v1.1.0.zip":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html>Please log in</html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	hfs, err := httpfs.NewHTTPFS(http.DefaultClient, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := modfs.New(hfs)
+
+	vfs, err := openVersion(t, m, "example.com/ct", "v1.0.0").OpenFS(modfs.VerifyContentType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vfs.Close()
+
+	_, err = openVersion(t, m, "example.com/ct", "v1.1.0").OpenFS(modfs.VerifyContentType())
+	if !errors.Is(err, modfs.ErrUnexpectedContentType) {
+		t.Fatalf("got %v, want ErrUnexpectedContentType", err)
+	}
+	if !strings.Contains(err.Error(), `unexpected content type "text/html`) {
+		t.Errorf("unclear error: %v", err)
+	}
+	// The page is not stored in the cache: the second read is checked too
+	cached := modfs.New(hfs, modfs.CacheDir(t.TempDir()))
+	for i := range 2 {
+		_, err = openVersion(t, cached, "example.com/ct", "v1.1.0").OpenFS(modfs.VerifyContentType())
+		if !errors.Is(err, modfs.ErrUnexpectedContentType) {
+			t.Errorf("cached, read %d: got %v, want ErrUnexpectedContentType", i+1, err)
+		}
+	}
+}
+
+func TestOpenFSContentTypeSignature(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/ct", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/ct\n",
+	})
+	addModule(t, proxy, "example.com/ct", "v1.1.0", map[string]string{
+		"go.mod": "module example.com/ct\n",
+	})
+	// Served without a content type, as from a cache
+	proxy["example.com/ct/@v/v1.1.0.zip"] = &fstest.MapFile{Data: []byte("<html>Please log in</html>")}
+
+	for name, fsys := range map[string]fs.FS{
+		"ReaderAt": proxy,
+		"stream":   &streamFS{FS: proxy},
+	} {
+		m := modfs.New(fsys)
+		for open, openFS := range map[string]func(*modfs.Version, ...modfs.OpenOption) (modfs.ZipFS, error){
+			"OpenFS":          (*modfs.Version).OpenFS,
+			"OpenFSStreaming": (*modfs.Version).OpenFSStreaming,
+		} {
+			vfs, err := openFS(openVersion(t, m, "example.com/ct", "v1.0.0"), modfs.VerifyContentType())
+			if err != nil {
+				t.Fatalf("%s, %s: %v", name, open, err)
+			}
+			vfs.Close()
+
+			_, err = openFS(openVersion(t, m, "example.com/ct", "v1.1.0"), modfs.VerifyContentType())
+			if !errors.Is(err, modfs.ErrUnexpectedContentType) {
+				t.Errorf("%s, %s: got %v, want ErrUnexpectedContentType", name, open, err)
+			}
+		}
+	}
+}
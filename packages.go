@@ -0,0 +1,210 @@
+package modfs
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/dolmen-go/modfs/internal/dirents"
+)
+
+// PackageFS exposes the Go packages of a module by import path.
+//
+// [fs.ReadDir] on the import path of a package returns its .go files, and
+// "importpath/file.go" opens a file of the package.
+// Directories that are not part of the module (testdata, vendor, hidden
+// directories, nested modules) are excluded.
+//
+// The directories leading to the packages (the root ".", then "example.com"
+// and "example.com/m" for the package "example.com/m/pkg") are synthesized,
+// and list the next element of the import paths of the packages below them,
+// so PackageFS can be walked with [fs.WalkDir] or [fs.Glob].
+type PackageFS struct {
+	fsys    fs.FS
+	modPath string
+	pkgs    map[string]string   // import path => directory in fsys
+	dirs    map[string][]string // directory => subdirectories leading to packages
+}
+
+// NewPackageFS builds a [PackageFS] from the content of a module, such as
+// returned by [Version.OpenFS]. The module path is read from go.mod.
+func NewPackageFS(fsys fs.FS) (*PackageFS, error) {
+	gomod, err := fs.ReadFile(fsys, "go.mod")
+	if err != nil {
+		return nil, err
+	}
+	modPath := modfile.ModulePath(gomod)
+	if modPath == "" {
+		return nil, errors.New("go.mod: missing module directive")
+	}
+
+	pkgs, err := findPackages(fsys, modPath)
+	if err != nil {
+		return nil, err
+	}
+	return &PackageFS{fsys: fsys, modPath: modPath, pkgs: pkgs, dirs: packageDirs(pkgs)}, nil
+}
+
+// findPackages returns the directories of the packages of the module
+// modPath in fsys, by import path.
+func findPackages(fsys fs.FS, modPath string) (map[string]string, error) {
+	pkgs := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == "." {
+				return nil
+			}
+			name := d.Name()
+			if name == "testdata" || name == "vendor" || name[0] == '.' || name[0] == '_' {
+				return fs.SkipDir
+			}
+			// Nested module
+			if _, err := fs.Stat(fsys, p+"/go.mod"); err == nil {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if isGoFile(d) {
+			dir := path.Dir(p)
+			pkgs[importPath(modPath, dir)] = dir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// packageDirs returns the directories leading to the packages, from the
+// root ".", with the names of their subdirectories.
+func packageDirs(pkgs map[string]string) map[string][]string {
+	dirs := map[string][]string{".": nil}
+	for pkg := range pkgs {
+		for p := pkg; p != "."; p = path.Dir(p) {
+			if _, ok := dirs[p]; !ok {
+				dirs[p] = nil
+			}
+			parent := path.Dir(p)
+			if name := path.Base(p); !slices.Contains(dirs[parent], name) {
+				dirs[parent] = append(dirs[parent], name)
+			}
+		}
+	}
+	return dirs
+}
+
+func isGoFile(d fs.DirEntry) bool {
+	return d.Type().IsRegular() && strings.HasSuffix(d.Name(), ".go")
+}
+
+func importPath(modPath, dir string) string {
+	if dir == "." {
+		return modPath
+	}
+	return modPath + "/" + dir
+}
+
+// ModulePath returns the module path declared in go.mod.
+func (p *PackageFS) ModulePath() string {
+	return p.modPath
+}
+
+// Packages returns the sorted import paths of the packages of the module.
+func (p *PackageFS) Packages() []string {
+	return slices.Sorted(maps.Keys(p.pkgs))
+}
+
+// ReadDir implements [fs.ReadDirFS]. For the import path of a package, the
+// .go files of the package are returned, with the subdirectories leading to
+// other packages. Entries are sorted by name.
+func (p *PackageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	subdirs, ok := p.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	var entries []fs.DirEntry
+	if dir, ok := p.pkgs[name]; ok {
+		files, err := fs.ReadDir(p.fsys, dir)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		entries = slices.DeleteFunc(files, func(d fs.DirEntry) bool { return !isGoFile(d) })
+	}
+	for _, sub := range subdirs {
+		entries = append(entries, &pkgDirInfo{name: sub})
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// Open implements [fs.FS]. name must be either a directory (see
+// [PackageFS]), or the path of a .go file of a package.
+func (p *PackageFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if _, ok := p.dirs[name]; ok {
+		entries, err := p.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &pkgDir{path: name, info: pkgDirInfo{name: path.Base(name)}, entries: entries}, nil
+	}
+
+	if dir, ok := p.pkgs[path.Dir(name)]; ok && strings.HasSuffix(name, ".go") {
+		f, err := p.fsys.Open(path.Join(dir, path.Base(name)))
+		if pe, ok := err.(*fs.PathError); ok {
+			pe.Path = name
+		}
+		return f, err
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// pkgDirInfo implements [fs.FileInfo] and [fs.DirEntry] for the directories
+// of a [PackageFS].
+type pkgDirInfo struct {
+	name string
+}
+
+func (i *pkgDirInfo) Name() string               { return i.name }
+func (i *pkgDirInfo) Size() int64                { return 0 }
+func (i *pkgDirInfo) Mode() fs.FileMode          { return fs.ModeDir | 0555 }
+func (i *pkgDirInfo) ModTime() time.Time         { return time.Time{} }
+func (i *pkgDirInfo) IsDir() bool                { return true }
+func (i *pkgDirInfo) Sys() any                   { return nil }
+func (i *pkgDirInfo) Type() fs.FileMode          { return fs.ModeDir }
+func (i *pkgDirInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// pkgDir implements [fs.ReadDirFile] for the directories of a [PackageFS].
+type pkgDir struct {
+	path    string
+	info    pkgDirInfo
+	entries dirents.Entries
+}
+
+func (d *pkgDir) Stat() (fs.FileInfo, error) { return &d.info, nil }
+func (d *pkgDir) Close() error               { return nil }
+
+func (d *pkgDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errors.New("is a directory")}
+}
+
+func (d *pkgDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	return d.entries.ReadDir(n)
+}
@@ -0,0 +1,109 @@
+package modfs_test
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestPackageFS(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/pkgs", "v1.0.0", map[string]string{
+		"go.mod":                     "module example.com/pkgs\n",
+		"pkgs.go":                    "package pkgs\n",
+		"pkgs_test.go":               "package pkgs\n",
+		"README.md":                  "# pkgs\n",
+		"internal/util/util.go":      "package util\n",
+		"sub/sub.go":                 "package sub\n",
+		"sub/nested/nested.go":       "package nested\n",
+		"sub/nested/data.json":       "{}",
+		"docs/index.md":              "# docs\n",
+		"testdata/x.go":              "package x\n",
+		"_examples/ex.go":            "package main\n",
+		"nestedmod/go.mod":           "module example.com/pkgs/nestedmod\n",
+		"nestedmod/nestedmod.go":     "package nestedmod\n",
+		"vendor/example.org/v/v.go":  "package v\n",
+		"internal/util/util_test.go": "package util\n",
+	})
+	ver := openVersion(t, modfs.New(proxy), "example.com/pkgs", "v1.0.0")
+	vfs, err := ver.OpenFS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vfs.Close()
+
+	pfs, err := modfs.NewPackageFS(vfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pfs.ModulePath() != "example.com/pkgs" {
+		t.Errorf("ModulePath: got %q", pfs.ModulePath())
+	}
+
+	wantPkgs := []string{
+		"example.com/pkgs",
+		"example.com/pkgs/internal/util",
+		"example.com/pkgs/sub",
+		"example.com/pkgs/sub/nested",
+	}
+	if got := pfs.Packages(); !slices.Equal(got, wantPkgs) {
+		t.Errorf("Packages: got %q, want %q", got, wantPkgs)
+	}
+
+	for pkg, want := range map[string][]string{
+		".":                              {"example.com"},
+		"example.com":                    {"pkgs"},
+		"example.com/pkgs":               {"internal", "pkgs.go", "pkgs_test.go", "sub"},
+		"example.com/pkgs/internal":      {"util"},
+		"example.com/pkgs/internal/util": {"util.go", "util_test.go"},
+		"example.com/pkgs/sub":           {"nested", "sub.go"},
+		"example.com/pkgs/sub/nested":    {"nested.go"},
+	} {
+		entries, err := fs.ReadDir(pfs, pkg)
+		if err != nil {
+			t.Errorf("%s: %v", pkg, err)
+			continue
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %q, want %q", pkg, got, want)
+		}
+	}
+
+	if err := fstest.TestFS(pfs,
+		"example.com/pkgs/pkgs.go",
+		"example.com/pkgs/pkgs_test.go",
+		"example.com/pkgs/internal/util/util.go",
+		"example.com/pkgs/internal/util/util_test.go",
+		"example.com/pkgs/sub/sub.go",
+		"example.com/pkgs/sub/nested/nested.go",
+	); err != nil {
+		t.Error(err)
+	}
+
+	b, err := fs.ReadFile(pfs, "example.com/pkgs/sub/nested/nested.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "package nested\n" {
+		t.Errorf("nested.go: got %q", b)
+	}
+
+	for _, name := range []string{
+		"example.com/pkgs/docs",
+		"example.com/pkgs/README.md",
+		"example.com/pkgs/sub/nested/data.json",
+		"example.com/pkgs/nestedmod",
+		"example.com/pkgs/testdata/x.go",
+	} {
+		if _, err := pfs.Open(name); err == nil {
+			t.Errorf("%s: unexpected success", name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package modfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/mod/module"
+)
+
+// defaultPrefetchWorkers is the number of parallel downloads of
+// [ModFS.PrefetchVersions] if [MaxConcurrency] is not set.
+const defaultPrefetchWorkers = 8
+
+// PrefetchVersions downloads in parallel the .info, .mod and .zip files of
+// the given module versions (such as the content of a go.sum), to populate
+// the cache of the backing FS (see [CacheDir]) before the modules are used,
+// possibly [Offline]. The number of parallel downloads is set by
+// [MaxConcurrency] (8 by default).
+//
+// All the versions are tried: the errors are joined with [errors.Join].
+// Cancelling ctx stops the downloads.
+func (m *ModFS) PrefetchVersions(ctx context.Context, versions []module.Version) error {
+	workers := m.maxConcurrency
+	if workers <= 0 {
+		workers = defaultPrefetchWorkers
+	}
+
+	jobs := make(chan module.Version)
+	var (
+		wg     sync.WaitGroup
+		errsMu sync.Mutex
+		errs   []error
+	)
+	for range min(workers, len(versions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for mv := range jobs {
+				if ctx.Err() != nil {
+					continue // Reported once below
+				}
+				if err := m.prefetch(ctx, mv); err != nil {
+					errsMu.Lock()
+					errs = append(errs, fmt.Errorf("%s@%s: %w", mv.Path, mv.Version, err))
+					errsMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, mv := range versions {
+		jobs <- mv
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// prefetch reads the files of the module version mv.
+func (m *ModFS) prefetch(ctx context.Context, mv module.Version) error {
+	escPath, err := escapePath(mv.Path)
+	if err != nil {
+		return err
+	}
+	mod := &Module{fs: m, escPath: escPath, Path: mv.Path}
+	escVersion, err := mod.escapeVersion(mv.Version)
+	if err != nil {
+		return err
+	}
+	for _, ext := range []string{".info", ".mod", ".zip"} {
+		f, err := m.open(ctx, mod.file("@v/"+escVersion+ext))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package modfs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/mod/module"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestPrefetchVersions(t *testing.T) {
+	proxy := fstest.MapFS{}
+	var versions []module.Version
+	for _, path := range []string{"example.com/a", "example.com/b", "example.com/Upper"} {
+		for _, v := range []string{"v1.0.0", "v1.1.0"} {
+			addModule(t, proxy, path, v, nil)
+			versions = append(versions, module.Version{Path: path, Version: v})
+		}
+	}
+	// Escaped paths
+	for name, f := range proxy {
+		if rest, ok := strings.CutPrefix(name, "example.com/Upper/"); ok {
+			proxy["example.com/!upper/"+rest] = f
+			delete(proxy, name)
+		}
+	}
+
+	cacheDir := t.TempDir()
+	m := modfs.New(proxy, modfs.CacheDir(cacheDir), modfs.MaxConcurrency(3))
+	if err := m.PrefetchVersions(context.Background(), versions); err != nil {
+		t.Fatal(err)
+	}
+	for _, mv := range versions {
+		escPath, _ := module.EscapePath(mv.Path)
+		for _, ext := range []string{".info", ".mod", ".zip"} {
+			name := escPath + "/@v/" + mv.Version + ext
+			if _, err := os.Stat(filepath.Join(cacheDir, filepath.FromSlash(name))); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	// Errors are joined
+	err := m.PrefetchVersions(context.Background(), []module.Version{
+		{Path: "example.com/a", Version: "v1.0.0"},
+		{Path: "example.com/missing", Version: "v1.0.0"},
+		{Path: "example.com/a", Version: "v9.0.0"},
+	})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got %v, want fs.ErrNotExist", err)
+	}
+	for _, s := range []string{"example.com/missing@v1.0.0", "example.com/a@v9.0.0"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("%s not reported in %q", s, err)
+		}
+	}
+	if strings.Contains(err.Error(), "example.com/a@v1.0.0") {
+		t.Errorf("example.com/a@v1.0.0 reported in %q", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.PrefetchVersions(ctx, versions); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled: got %v", err)
+	}
+}
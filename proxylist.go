@@ -0,0 +1,71 @@
+package modfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// Backend is a proxy of a [ProxyList].
+type Backend struct {
+	FS fs.FS
+	// FallbackOnError makes the next backend tried on any error, like the
+	// "|" separator in GOPROXY. By default, like the "," separator, the next
+	// backend is tried only if the file doesn't exist ([fs.ErrNotExist]).
+	//
+	// [github.com/dolmen-go/modfs/httpfs.HTTPFS] reports the 404 (Not Found)
+	// and 410 (Gone) statuses as fs.ErrNotExist, so the fallback matches the
+	// one of the go command.
+	FallbackOnError bool
+}
+
+// ProxyList is an [fs.FS] that tries a list of proxies in order, with the
+// fallback rules of the GOPROXY environment variable. For example
+// "https://a.example.com|https://b.example.com,https://c.example.com" is:
+//
+//	modfs.NewProxyList([]modfs.Backend{
+//		{FS: a, FallbackOnError: true},
+//		{FS: b},
+//		{FS: c},
+//	})
+//
+// ProxyList implements [ContextFS].
+type ProxyList struct {
+	backends []Backend
+}
+
+// NewProxyList returns a [ProxyList] over the given backends.
+func NewProxyList(backends []Backend) *ProxyList {
+	return &ProxyList{backends: backends}
+}
+
+// Open implements [fs.FS].
+func (p *ProxyList) Open(name string) (fs.File, error) {
+	return p.OpenContext(context.Background(), name)
+}
+
+// OpenContext implements [ContextFS]. The context is passed to backends
+// that implement ContextFS.
+//
+// If all backends fail, the error of the last one tried is returned.
+func (p *ProxyList) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	err := error(&fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}) // Empty list
+	for _, b := range p.backends {
+		var f fs.File
+		if cfs, ok := b.FS.(ContextFS); ok {
+			f, err = cfs.OpenContext(ctx, name)
+		} else {
+			f, err = b.FS.Open(name)
+		}
+		if err == nil {
+			return f, nil
+		}
+		if ctx.Err() != nil || !(b.FallbackOnError || errors.Is(err, fs.ErrNotExist)) {
+			break
+		}
+	}
+	return nil, err
+}
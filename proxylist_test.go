@@ -0,0 +1,68 @@
+package modfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+// errFS fails to open any file.
+type errFS struct {
+	err error
+}
+
+func (e errFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: e.err}
+}
+
+func TestProxyList(t *testing.T) {
+	a := fstest.MapFS{}
+	addModule(t, a, "example.com/a", "v1.0.0", nil)
+	b := fstest.MapFS{}
+	addModule(t, b, "example.com/b", "v1.0.0", nil)
+	errDown := errors.New("proxy down")
+	down := errFS{errDown}
+	notFound := errFS{fs.ErrNotExist}
+
+	tests := []struct {
+		name     string
+		backends []modfs.Backend
+		want     map[string]error // module path => error
+	}{
+		{"comma", []modfs.Backend{{FS: a}, {FS: b}}, map[string]error{
+			"example.com/a": nil,
+			"example.com/b": nil,
+			"example.com/c": fs.ErrNotExist,
+		}},
+		{"comma/error", []modfs.Backend{{FS: down}, {FS: b}}, map[string]error{
+			"example.com/b": errDown,
+		}},
+		{"pipe/error", []modfs.Backend{{FS: down, FallbackOnError: true}, {FS: b}}, map[string]error{
+			"example.com/b": nil,
+		}},
+		{"pipe/last", []modfs.Backend{{FS: a, FallbackOnError: true}, {FS: down}}, map[string]error{
+			"example.com/a": nil,
+			"example.com/b": errDown,
+		}},
+		{"comma/notfound", []modfs.Backend{{FS: notFound}, {FS: a}}, map[string]error{
+			"example.com/a": nil,
+		}},
+		{"empty", nil, map[string]error{
+			"example.com/a": fs.ErrNotExist,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := modfs.New(modfs.NewProxyList(tt.backends), modfs.RequireLatest())
+			for path, want := range tt.want {
+				_, err := m.OpenModule(path)
+				if want == nil && err != nil || !errors.Is(err, want) {
+					t.Errorf("%s: got %v, want %v", path, err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package modfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// ErrUnknownRevision is returned by [Module.VersionAtCommit] when the proxy
+// doesn't know the commit.
+var ErrUnknownRevision = errors.New("unknown revision")
+
+// VersionAtCommit returns the version of the module at the given commit,
+// identified by its time and its revision (commit hash, at least 12 hex
+// digits).
+//
+// The version is resolved using the canonical pseudo-version form for
+// commits without a base tag: vX.0.0-yyyymmddhhmmss-abcdefabcdef, where
+// vX is the major version of the module path. Pseudo-versions derived
+// from a tag (such as v1.2.4-0.yyyymmddhhmmss-abcdefabcdef) can be resolved
+// with [Module.Version].
+func (m *Module) VersionAtCommit(commitTime time.Time, rev string) (*Version, error) {
+	if commitTime.IsZero() {
+		return nil, fmt.Errorf("%s: missing commit time", m.Path)
+	}
+	if len(rev) < 12 || strings.Trim(rev, "0123456789abcdef") != "" {
+		return nil, fmt.Errorf("%s: invalid revision %q", m.Path, rev)
+	}
+	_, pathMajor, ok := module.SplitPathVersion(m.Path)
+	if !ok {
+		return nil, fmt.Errorf("%s: invalid module path", m.Path)
+	}
+
+	v := module.PseudoVersion(module.PathMajorPrefix(pathMajor), "", commitTime, rev[:12])
+	ver, err := m.Version(v)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s@%s: %w: %w", m.Path, v, ErrUnknownRevision, err)
+	}
+	return ver, err
+}
@@ -0,0 +1,233 @@
+package modfs
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// streamingTailSize is the size of the end of the archive fetched first by
+// [Version.OpenFSStreaming]. It should contain the central directory of
+// most modules.
+const streamingTailSize = 256 << 10
+
+// streamingChunkSize is the size of the reads of the download of
+// [Version.OpenFSStreaming].
+const streamingChunkSize = 32 << 10
+
+// OpenFSStreaming is like [Version.OpenFS], but allows to read the module
+// while the zip archive is still downloading.
+//
+// The archive is downloaded sequentially in the background to a temporary
+// file. If the backing FS provides random access to the file ([io.ReaderAt],
+// such as an [github.com/dolmen-go/modfs/httpfs.HTTPFS] with a server
+// supporting Range requests), the end of the archive, which holds the
+// central directory, is fetched first with a separate read. ReadDir
+// (including the [fs.FileInfo] of entries) is then served as soon as the
+// central directory is available, but opening files blocks until their data
+// has been downloaded.
+//
+// The options are applied after the [DefaultOpenOptions] of the [ModFS], as
+// with OpenFS. The hash of the archive can only be checked once downloaded:
+// with [ExpectZipSHA256] or [VerifyZipHash], and for the files of the local
+// file system (such as a [CacheDir] hit), this is the same as OpenFS.
+//
+// Constraints:
+//   - the size of the archive must be known from Stat;
+//   - if the central directory is larger than the prefetched tail, or if the
+//     file doesn't provide random access, OpenFSStreaming waits for the end
+//     of the download;
+//   - if the download fails, reads of data not yet downloaded fail.
+func (ver *Version) OpenFSStreaming(opts ...OpenOption) (ZipFS, error) {
+	o := ver.module.fs.newOpenOptions(opts)
+	if o.zipSHA256 != nil || o.verifyZipHash || o.err != nil {
+		return ver.OpenFS(opts...)
+	}
+
+	zipPath := ver.file(".zip")
+
+	// The download outlives this call: it is stopped by streamReader.Close
+	ctx, cancel := context.WithCancel(context.Background())
+	f, err := ver.module.fs.open(ctx, zipPath)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if isLocalFile(f) {
+		f.Close()
+		cancel()
+		return ver.OpenFS(opts...)
+	}
+	checkSignature := false
+	if o.verifyContentType {
+		if err := checkContentType(f); err != nil {
+			f.Close()
+			cancel()
+			return nil, &fs.PathError{Op: "open", Path: zipPath, Err: err}
+		}
+		checkSignature = contentType(f) == ""
+	}
+	fi, err := f.Stat()
+	if err != nil || fi.Size() < minZipSize {
+		// Unknown size
+		f.Close()
+		cancel()
+		return ver.OpenFS(opts...)
+	}
+	size := fi.Size()
+
+	tmp, err := ver.module.fs.createTemp()
+	if err != nil {
+		f.Close()
+		cancel()
+		return nil, fmt.Errorf("%v: %w", zipPath, err)
+	}
+
+	sr := &streamReader{m: ver.module.fs, src: f, tmp: tmp, cancel: cancel, stop: make(chan struct{}), done: make(chan struct{})}
+	sr.cond.L = &sr.mu
+	if ra, ok := f.(io.ReaderAt); ok && size > streamingTailSize {
+		// Start the download first: with HTTP, the initial response is kept
+		// for the download and the tail is fetched with a Range request.
+		if sr.readChunk(make([]byte, streamingChunkSize)) == nil {
+			sr.fetchTail(ra, size)
+		}
+	}
+	go sr.download()
+
+	if checkSignature {
+		// Waits for the first chunk of the download
+		if err := checkZipSignature(sr); err != nil {
+			sr.Close()
+			return nil, &fs.PathError{Op: "open", Path: zipPath, Err: err}
+		}
+	}
+
+	zr, err := zip.NewReader(sr, size)
+	if err != nil {
+		sr.Close()
+		return nil, err
+	}
+	if err := ver.checkRegular(zr, o); err != nil {
+		sr.Close()
+		return nil, err
+	}
+	return ver.zipFS(zr, sr, o)
+}
+
+// streamReader is an [io.ReaderAt] over a file being downloaded to a
+// temporary file. Reads block until the requested data is available.
+//
+// src is owned by the goroutine running download, which closes it.
+type streamReader struct {
+	m      *ModFS
+	src    fs.File
+	tmp    *os.File
+	cancel context.CancelFunc // cancels the context of src
+
+	tailOff int64
+	tail    []byte // end of the file, fetched first
+
+	mu      sync.Mutex
+	cond    sync.Cond
+	written int64 // bytes available in tmp
+	err     error // download error (io.EOF when complete)
+
+	stop     chan struct{} // closed by Close
+	stopOnce sync.Once
+	done     chan struct{} // closed when download ends
+}
+
+// fetchTail reads the end of the file, before the download is started.
+// On failure, the tail is ignored.
+func (sr *streamReader) fetchTail(ra io.ReaderAt, size int64) {
+	off := size - streamingTailSize
+	tail := make([]byte, streamingTailSize)
+	if _, err := ra.ReadAt(tail, off); err != nil {
+		return
+	}
+	sr.tailOff, sr.tail = off, tail
+}
+
+// readChunk reads the next chunk of src to tmp.
+func (sr *streamReader) readChunk(buf []byte) error {
+	n, err := sr.src.Read(buf)
+	if n > 0 {
+		if _, werr := sr.tmp.WriteAt(buf[:n], sr.written); werr != nil {
+			err = werr
+		}
+	}
+	sr.mu.Lock()
+	sr.written += int64(n)
+	if err != nil {
+		sr.err = err
+	}
+	sr.cond.Broadcast()
+	sr.mu.Unlock()
+	return err
+}
+
+func (sr *streamReader) download() {
+	defer close(sr.done)
+	defer sr.src.Close()
+
+	if sr.err != nil { // Failure of the first chunk
+		return
+	}
+	buf := make([]byte, streamingChunkSize)
+	for {
+		select {
+		case <-sr.stop:
+			sr.mu.Lock()
+			sr.err = os.ErrClosed
+			sr.cond.Broadcast()
+			sr.mu.Unlock()
+			return
+		default:
+		}
+		if sr.readChunk(buf) != nil {
+			return
+		}
+	}
+}
+
+// ReadAt implements [io.ReaderAt].
+func (sr *streamReader) ReadAt(p []byte, off int64) (int, error) {
+	if sr.tail != nil && off >= sr.tailOff {
+		n := copy(p, sr.tail[off-sr.tailOff:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+
+	end := off + int64(len(p))
+	sr.mu.Lock()
+	for sr.written < end && sr.err == nil {
+		sr.cond.Wait()
+	}
+	written, err := sr.written, sr.err
+	sr.mu.Unlock()
+
+	if written < end {
+		if err != io.EOF {
+			return 0, err
+		}
+		// Complete download, but short: read what's available
+	}
+	return sr.tmp.ReadAt(p, off)
+}
+
+// Close stops the download and removes the temporary file.
+func (sr *streamReader) Close() error {
+	sr.stopOnce.Do(func() {
+		close(sr.stop)
+		sr.cancel() // Interrupts a pending Read of src
+	})
+	<-sr.done
+
+	return sr.m.removeTemp(sr.tmp)
+}
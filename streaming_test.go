@@ -0,0 +1,207 @@
+package modfs_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dolmen-go/modfs"
+	"github.com/dolmen-go/modfs/httpfs"
+)
+
+// streamingServer serves a proxy over HTTP. The full download of the zip
+// archive stalls after the first half of the content until release is
+// closed. Range requests are supported if ranges is set.
+type streamingServer struct {
+	*httptest.Server
+	release   chan struct{}
+	downloads atomic.Int32 // requests for the full zip
+	ranges    atomic.Int32 // Range requests for the zip
+}
+
+func newStreamingServer(t *testing.T, proxy fstest.MapFS, zipPath string, ranges bool) *streamingServer {
+	s := &streamingServer{release: make(chan struct{})}
+	proxyHandler := http.FileServerFS(proxy)
+	data := proxy[zipPath].Data
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+zipPath {
+			proxyHandler.ServeHTTP(w, r)
+			return
+		}
+		if ranges && r.Header.Get("Range") != "" {
+			s.ranges.Add(1)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+			return
+		}
+		s.downloads.Add(1)
+		if ranges {
+			w.Header().Set("Accept-Ranges", "bytes")
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data[:len(data)/2])
+		w.(http.Flusher).Flush()
+		select {
+		case <-s.release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Write(data[len(data)/2:])
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func streamingVersion(t *testing.T, ranges bool) (*modfs.Version, *streamingServer, []byte) {
+	big := make([]byte, 1<<20)
+	rand.NewChaCha8([32]byte{}).Read(big)
+
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/stream", "v1.0.0", map[string]string{
+		"go.mod":   "module example.com/stream\n",
+		"big.bin":  string(big),
+		"small.go": "package stream\n",
+	})
+	server := newStreamingServer(t, proxy, "example.com/stream/@v/v1.0.0.zip", ranges)
+	hfs, err := httpfs.NewHTTPFS(server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return openVersion(t, modfs.New(hfs), "example.com/stream", "v1.0.0"), server, big
+}
+
+func TestOpenFSStreaming(t *testing.T) {
+	ver, server, big := streamingVersion(t, true)
+
+	done := make(chan struct{})
+	var vfs modfs.ZipFS
+	var err error
+	go func() {
+		defer close(done)
+		vfs, err = ver.OpenFSStreaming(modfs.VerifyContentType())
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		close(server.release)
+		t.Fatal("OpenFSStreaming waited for the download")
+	}
+	if err != nil {
+		close(server.release)
+		t.Fatal(err)
+	}
+	defer vfs.Close()
+
+	// The directory is available before the download
+	entries, err := fs.ReadDir(vfs, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	fi, err := entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "big.bin" || fi.Size() != int64(len(big)) {
+		t.Errorf("got %s (size %d), want big.bin (size %d)", fi.Name(), fi.Size(), len(big))
+	}
+
+	// Let the download proceed
+	close(server.release)
+	b, err := vfs.ReadFile("big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, big) {
+		t.Error("big.bin: content mismatch")
+	}
+	if n := server.downloads.Load(); n != 1 {
+		t.Errorf("got %d downloads, want 1", n)
+	}
+	if n := server.ranges.Load(); n != 1 {
+		t.Errorf("got %d Range requests, want 1", n)
+	}
+}
+
+func TestOpenFSStreamingNoRanges(t *testing.T) {
+	ver, server, big := streamingVersion(t, false)
+	close(server.release)
+
+	vfs, err := ver.OpenFSStreaming()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vfs.Close()
+	b, err := vfs.ReadFile("big.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, big) {
+		t.Error("big.bin: content mismatch")
+	}
+	if err := fstest.TestFS(vfs, "go.mod", "small.go", "big.bin"); err != nil {
+		t.Error(err)
+	}
+	// Without Range requests, the archive is downloaded once
+	if n := server.downloads.Load(); n != 1 {
+		t.Errorf("got %d downloads, want 1", n)
+	}
+}
+
+func TestOpenFSStreamingClose(t *testing.T) {
+	ver, server, _ := streamingVersion(t, true)
+
+	vfs, err := ver.OpenFSStreaming()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The download is stalled: Close interrupts it
+	closed := make(chan error)
+	go func() { closed <- vfs.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		close(server.release)
+		t.Fatal("Close waited for the download")
+	}
+}
+
+func TestOpenFSStreamingFallback(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/stream", "v1.0.0", map[string]string{
+		"go.mod":    "module example.com/stream\n",
+		"stream.go": "package stream\n",
+	})
+	sum := sha256.Sum256(proxy["example.com/stream/@v/v1.0.0.zip"].Data)
+	zipSHA256 := hex.EncodeToString(sum[:])
+	for name, fsys := range map[string]fs.FS{
+		"ReaderAt": proxy,
+		"stream":   &streamFS{FS: proxy},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ver := openVersion(t, modfs.New(fsys), "example.com/stream", "v1.0.0")
+			vfs, err := ver.OpenFSStreaming(modfs.ExpectZipSHA256(zipSHA256))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer vfs.Close()
+			if err := fstest.TestFS(vfs, "go.mod", "stream.go"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
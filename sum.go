@@ -0,0 +1,173 @@
+package modfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// Hash returns the "h1:" hash of the content of the module, as recorded in
+// go.sum files.
+//
+// The [DefaultOpenOptions] of the [ModFS] apply.
+func (ver *Version) Hash() (string, error) {
+	o := ver.module.fs.newOpenOptions(nil)
+	// With VerifyZipHash, check the hash computed below instead of hashing twice
+	verify := o.verifyZipHash
+	o.verifyZipHash = false
+	var expected string
+	if verify {
+		var err error
+		if expected, err = ver.zipHash(context.Background()); err != nil {
+			return "", err
+		}
+	}
+
+	zr, closer, err := ver.openZip(context.Background(), o)
+	if err != nil {
+		return "", err
+	}
+	defer closer.Close()
+	h, err := ver.hashZip(zr)
+	if err == nil && verify {
+		err = ver.checkHash(h, expected)
+	}
+	if err != nil {
+		return "", err
+	}
+	return h, nil
+}
+
+// hashZip computes the "h1:" hash of the zip archive of the module, the same
+// way as [dirhash.HashZip].
+func (ver *Version) hashZip(zr *zip.Reader) (string, error) {
+	files := make([]string, len(zr.File))
+	zfiles := make(map[string]*zip.File, len(zr.File))
+	for i, f := range zr.File {
+		files[i] = f.Name
+		zfiles[f.Name] = f
+	}
+	h, err := dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		return zfiles[name].Open()
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", ver.file(".zip"), err)
+	}
+	return h, nil
+}
+
+// VerifyHash checks that the "h1:" hash of the content of the module (see
+// [Version.Hash]) is expected. If it doesn't match, the error wraps
+// [ErrHashMismatch].
+func (ver *Version) VerifyHash(expected string) error {
+	h, err := ver.Hash()
+	if err != nil {
+		return err
+	}
+	return ver.checkHash(h, expected)
+}
+
+func (ver *Version) checkHash(h, expected string) error {
+	if h != expected {
+		return fmt.Errorf("%s@%s: %w: got %s, want %s", ver.module.Path, ver.Version, ErrHashMismatch, h, expected)
+	}
+	return nil
+}
+
+// ZipHash returns the "h1:" hash of the content of the module, as served in
+// $module/@v/$version.ziphash by proxies using the layout of the module
+// cache (see [Capabilities].ZipHash).
+func (ver *Version) ZipHash() (string, error) {
+	return ver.zipHash(context.Background())
+}
+
+// CachedZipHash returns the "h1:" hash of the content of the module from
+// the .ziphash sidecar file of a module cache (GOMODCACHE/cache/download),
+// which records the hash already verified by the go command. ok is false if
+// the file is absent.
+func (ver *Version) CachedZipHash() (h string, ok bool, err error) {
+	h, err = ver.zipHash(context.Background())
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return h, true, nil
+}
+
+func (ver *Version) zipHash(ctx context.Context) (string, error) {
+	b, err := ver.module.fs.readFile(ctx, ver.file(".ziphash"))
+	if err != nil {
+		return "", err
+	}
+	h := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(h, "h1:") {
+		return "", fmt.Errorf("%s: invalid hash %q", ver.file(".ziphash"), h)
+	}
+	return h, nil
+}
+
+// GoModHash returns the "h1:" hash of go.mod, as recorded in go.sum files.
+func (ver *Version) GoModHash() (string, error) {
+	gomod, err := ver.GoMod()
+	if err != nil {
+		return "", err
+	}
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(gomod)), nil
+	})
+}
+
+// SumLines returns the lines recorded in go.sum for the module: the hash of
+// the content of the module and the hash of its go.mod.
+func (ver *Version) SumLines() ([]string, error) {
+	h, err := ver.Hash()
+	if err != nil {
+		return nil, err
+	}
+	hmod, err := ver.GoModHash()
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		ver.module.Path + " " + ver.Version + " " + h,
+		ver.module.Path + " " + ver.Version + "/go.mod " + hmod,
+	}, nil
+}
+
+// BuildGoSum returns the content of a go.sum file with the lines of the given
+// versions (see [Version.SumLines]), sorted like the go command does.
+func BuildGoSum(versions []*Version) ([]byte, error) {
+	keys := make([]module.Version, 0, 2*len(versions))
+	sums := make(map[module.Version]string, 2*len(versions))
+	for _, ver := range versions {
+		lines, err := ver.SumLines()
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			f := strings.Fields(line) // path, version, hash
+			mv := module.Version{Path: f[0], Version: f[1]}
+			if _, dup := sums[mv]; !dup {
+				keys = append(keys, mv)
+			}
+			sums[mv] = f[2]
+		}
+	}
+	module.Sort(keys)
+
+	var buf bytes.Buffer
+	for _, mv := range keys {
+		fmt.Fprintf(&buf, "%s %s %s\n", mv.Path, mv.Version, sums[mv])
+	}
+	return buf.Bytes(), nil
+}
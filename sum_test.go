@@ -0,0 +1,162 @@
+package modfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+func TestBuildGoSum(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/b", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/b\n",
+		"b.go":   "package b\n",
+	})
+	addModule(t, proxy, "example.com/a", "v0.10.0", map[string]string{
+		"go.mod": "module example.com/a\n\ngo 1.21\n",
+		"a.go":   "package a\n",
+	})
+	addModule(t, proxy, "example.com/a", "v0.9.0", map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n",
+	})
+	m := modfs.New(proxy)
+	versions := []*modfs.Version{
+		openVersion(t, m, "example.com/b", "v1.0.0"),
+		openVersion(t, m, "example.com/a", "v0.10.0"),
+		openVersion(t, m, "example.com/a", "v0.9.0"),
+	}
+
+	gosum, err := modfs.BuildGoSum(versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("go.sum:\n%s", gosum)
+
+	lineRe := regexp.MustCompile(`^(\S+) (v\S+?)(/go\.mod)? h1:[A-Za-z0-9+/]{43}=$`)
+	var order []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(gosum), "\n"), "\n") {
+		m := lineRe.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("invalid go.sum line %q", line)
+		}
+		order = append(order, m[1]+" "+m[2]+m[3])
+	}
+	want := []string{
+		"example.com/a v0.9.0",
+		"example.com/a v0.9.0/go.mod",
+		"example.com/a v0.10.0",
+		"example.com/a v0.10.0/go.mod",
+		"example.com/b v1.0.0",
+		"example.com/b v1.0.0/go.mod",
+	}
+	if strings.Join(order, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got lines:\n%s\nwant:\n%s", strings.Join(order, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Check the hash of the content against the reference implementation
+	zipFile := filepath.Join(t.TempDir(), "b.zip")
+	if err := os.WriteFile(zipFile, proxy["example.com/b/@v/v1.0.0.zip"].Data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := dirhash.HashZip(zipFile, dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gosum), "example.com/b v1.0.0 "+h+"\n") {
+		t.Errorf("missing %s for example.com/b v1.0.0", h)
+	}
+}
+
+func TestVerifyHash(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/h", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/h\n",
+		"h.go":   "package h\n",
+	})
+	zipFile := filepath.Join(t.TempDir(), "h.zip")
+	if err := os.WriteFile(zipFile, proxy["example.com/h/@v/v1.0.0.zip"].Data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := dirhash.HashZip(zipFile, dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const bad = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+	ver := openVersion(t, modfs.New(proxy), "example.com/h", "v1.0.0")
+	if err := ver.VerifyHash(want); err != nil {
+		t.Errorf("VerifyHash: %v", err)
+	}
+	if err := ver.VerifyHash(bad); !errors.Is(err, modfs.ErrHashMismatch) {
+		t.Errorf("VerifyHash: got %v, want ErrHashMismatch", err)
+	}
+
+	t.Run("ziphash", func(t *testing.T) {
+		// No .ziphash
+		if _, err := ver.OpenFS(modfs.VerifyZipHash()); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("got %v, want fs.ErrNotExist", err)
+		}
+
+		proxy["example.com/h/@v/v1.0.0.ziphash"] = &fstest.MapFile{Data: []byte(want + "\n")}
+		if h, err := ver.ZipHash(); err != nil || h != want {
+			t.Errorf("ZipHash: got %q, %v", h, err)
+		}
+		fsys, err := ver.OpenFS(modfs.VerifyZipHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys.Close()
+
+		proxy["example.com/h/@v/v1.0.0.ziphash"] = &fstest.MapFile{Data: []byte(bad)}
+		if _, err := ver.OpenFS(modfs.VerifyZipHash()); !errors.Is(err, modfs.ErrHashMismatch) {
+			t.Errorf("got %v, want ErrHashMismatch", err)
+		}
+
+		// The default options apply to Hash
+		dver := openVersion(t, modfs.New(proxy, modfs.DefaultOpenOptions(modfs.VerifyZipHash())), "example.com/h", "v1.0.0")
+		if _, err := dver.Hash(); !errors.Is(err, modfs.ErrHashMismatch) {
+			t.Errorf("Hash: got %v, want ErrHashMismatch", err)
+		}
+		proxy["example.com/h/@v/v1.0.0.ziphash"] = &fstest.MapFile{Data: []byte(want + "\n")}
+		if h, err := dver.Hash(); err != nil || h != want {
+			t.Errorf("Hash: got %q, %v", h, err)
+		}
+	})
+}
+
+func TestCachedZipHash(t *testing.T) {
+	const h = "h1:z+FBa3FEszY05z0yrV2jOellRIYVpP0EJKCPDj89NOw="
+
+	// Layout of GOMODCACHE/cache/download
+	cache := fstest.MapFS{}
+	addModule(t, cache, "example.com/cached", "v1.0.0", nil)
+	addModule(t, cache, "example.com/cached", "v1.1.0", nil)
+	cache["example.com/cached/@v/v1.0.0.ziphash"] = &fstest.MapFile{Data: []byte(h + "\n")}
+	cache["example.com/cached/@v/v1.0.0.lock"] = &fstest.MapFile{}
+	addModule(t, cache, "example.com/bad", "v1.0.0", nil)
+	cache["example.com/bad/@v/v1.0.0.ziphash"] = &fstest.MapFile{Data: []byte("garbage")}
+	m := modfs.New(cache)
+
+	got, ok, err := openVersion(t, m, "example.com/cached", "v1.0.0").CachedZipHash()
+	if err != nil || !ok || got != h {
+		t.Errorf("v1.0.0: got (%q, %t, %v), want (%q, true, nil)", got, ok, err, h)
+	}
+
+	got, ok, err = openVersion(t, m, "example.com/cached", "v1.1.0").CachedZipHash()
+	if err != nil || ok || got != "" {
+		t.Errorf("v1.1.0: got (%q, %t, %v), want (\"\", false, nil)", got, ok, err)
+	}
+
+	if _, _, err := openVersion(t, m, "example.com/bad", "v1.0.0").CachedZipHash(); err == nil {
+		t.Error("bad: no error for an invalid hash")
+	}
+}
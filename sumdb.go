@@ -0,0 +1,227 @@
+package modfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+)
+
+// NoSumDB sets the modules whose checksums must not be verified with a
+// checksum database, such as private modules unknown to sum.golang.org.
+// patterns is a comma-separated list of glob patterns (in the syntax of
+// [path.Match]) matched against the prefixes of module paths, as in the
+// GONOSUMDB and GOPRIVATE environment variables of the go command:
+//
+//	modfs.New(fsys, modfs.NoSumDB(os.Getenv("GOPRIVATE")))
+//
+// The option can be given several times: the patterns are added.
+//
+// See [ModFS.SkipSumDB].
+func NoSumDB(patterns string) Option {
+	return func(m *ModFS) {
+		if m.noSumDB != "" && patterns != "" {
+			m.noSumDB += ","
+		}
+		m.noSumDB += patterns
+	}
+}
+
+// SkipSumDB reports whether the checksums of the module at modulePath must
+// not be verified with a checksum database, because modulePath matches the
+// patterns set with [NoSumDB].
+func (m *ModFS) SkipSumDB(modulePath string) bool {
+	return module.MatchPrefixPatterns(m.noSumDB, modulePath)
+}
+
+// SumDB is a checksum database (such as sum.golang.org) accessed through a
+// proxy, as described in the GOPROXY protocol:
+// $GOPROXY/sumdb/$name/... where $name is the name of the database.
+type SumDB struct {
+	name string
+	key  string
+	fs   fs.FS
+
+	mu        sync.Mutex
+	supported *bool // cached result of the probe of the supported endpoint
+
+	clientOnce sync.Once
+	client     *sumdb.Client
+	opsMu      sync.Mutex
+	latest     []byte            // latest signed tree head, in memory
+	cache      map[string][]byte // tiles and lookups already verified
+}
+
+// NewSumDB returns the checksum database identified by its verifier key
+// (as in GOSUMDB, for example
+// "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ax18htTTAD8OuAn8"),
+// accessed through the proxy fsys.
+//
+// A key with just the name of the database is accepted, but
+// [Version.VerifyWithSumDB] requires the full key.
+func NewSumDB(key string, fsys fs.FS) (*SumDB, error) {
+	name, _, _ := strings.Cut(key, "+")
+	if name == "" || !fs.ValidPath(name) || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid sumdb key %q", key)
+	}
+	return &SumDB{name: name, key: key, fs: fsys}, nil
+}
+
+// Name returns the name of the checksum database, such as "sum.golang.org".
+func (db *SumDB) Name() string {
+	return db.name
+}
+
+// Supported reports whether the checksum database can be queried through
+// the proxy.
+//
+// The answer doesn't depend on the module: the proxy advertises the support
+// of the whole database ($GOPROXY/sumdb/$name/supported), so Supported takes
+// no module path. Modules that must not be checked against the database,
+// such as private modules, are excluded with [NoSumDB].
+//
+// The proxy is queried only once: the result is cached. If the proxy doesn't support the database, verification
+// should be skipped (or done by querying the database directly).
+func (db *SumDB) Supported() (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.supported == nil {
+		p := "sumdb/" + db.name + "/supported"
+		f, err := db.fs.Open(p)
+		switch {
+		case err == nil:
+			f.Close()
+		case errors.Is(err, fs.ErrNotExist):
+		default:
+			return false, err
+		}
+		supported := err == nil
+		db.supported = &supported
+	}
+	return *db.supported, nil
+}
+
+// lookup returns the "h1:" hash recorded in the checksum database for the
+// given module version (vers may have the "/go.mod" suffix). The proofs
+// returned by the database are verified with the key.
+func (db *SumDB) lookup(path, vers string) (string, error) {
+	db.clientOnce.Do(func() {
+		db.client = sumdb.NewClient(&sumdbOps{db})
+	})
+	lines, err := db.client.Lookup(path, vers)
+	if err != nil {
+		return "", err
+	}
+	// Lookup returns only the lines of path@vers: "path vers hash"
+	if len(lines) != 1 {
+		return "", fmt.Errorf("%s: %d records for %s@%s", db.name, len(lines), path, vers)
+	}
+	f := strings.Fields(lines[0])
+	if len(f) != 3 {
+		return "", fmt.Errorf("%s: invalid record %q", db.name, lines[0])
+	}
+	return f[2], nil
+}
+
+// sumdbOps implements [sumdb.ClientOps] for a [SumDB]: the database is
+// accessed through the proxy, and the state of the client (the latest tree
+// head and the cache) is kept in memory.
+type sumdbOps struct {
+	db *SumDB
+}
+
+func (ops *sumdbOps) ReadRemote(path string) ([]byte, error) {
+	return fs.ReadFile(ops.db.fs, "sumdb/"+ops.db.name+path)
+}
+
+func (ops *sumdbOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(ops.db.key), nil
+	}
+	if file != ops.db.name+"/latest" {
+		return nil, fmt.Errorf("unknown config %s", file)
+	}
+	ops.db.opsMu.Lock()
+	defer ops.db.opsMu.Unlock()
+	// Empty on first use
+	return ops.db.latest, nil
+}
+
+func (ops *sumdbOps) WriteConfig(file string, old, new []byte) error {
+	ops.db.opsMu.Lock()
+	defer ops.db.opsMu.Unlock()
+	if !bytes.Equal(ops.db.latest, old) {
+		return sumdb.ErrWriteConflict
+	}
+	ops.db.latest = new
+	return nil
+}
+
+func (ops *sumdbOps) ReadCache(file string) ([]byte, error) {
+	ops.db.opsMu.Lock()
+	defer ops.db.opsMu.Unlock()
+	data, ok := ops.db.cache[file]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (ops *sumdbOps) WriteCache(file string, data []byte) {
+	ops.db.opsMu.Lock()
+	defer ops.db.opsMu.Unlock()
+	if ops.db.cache == nil {
+		ops.db.cache = make(map[string][]byte)
+	}
+	ops.db.cache[file] = data
+}
+
+func (ops *sumdbOps) Log(msg string) {}
+
+// SecurityError is called when the database is inconsistent: the operation
+// then fails with [sumdb.ErrSecurity].
+func (ops *sumdbOps) SecurityError(msg string) {}
+
+// VerifyWithSumDB checks the hashes of the content and of the go.mod of the
+// module against the records of the checksum database db, like the go
+// command does when it downloads a module. The records are authenticated
+// with the verifier key of db (see [NewSumDB]) and the proofs of
+// inclusion in the database are verified, so a proxy can't serve altered
+// content. If a hash doesn't match, the error wraps [ErrHashMismatch].
+//
+// Verification is skipped for the modules matching [NoSumDB].
+//
+// The state of the database (its latest tree head) is kept in db for its
+// lifetime: reuse db to detect a proxy serving inconsistent views of the
+// database.
+func (ver *Version) VerifyWithSumDB(db *SumDB) error {
+	path := ver.module.Path
+	if ver.module.fs.SkipSumDB(path) {
+		return nil
+	}
+
+	want, err := db.lookup(path, ver.Version+"/go.mod")
+	if err != nil {
+		return err
+	}
+	h, err := ver.GoModHash()
+	if err != nil {
+		return err
+	}
+	if h != want {
+		return fmt.Errorf("%s@%s/go.mod: %w: got %s, want %s", path, ver.Version, ErrHashMismatch, h, want)
+	}
+
+	if want, err = db.lookup(path, ver.Version); err != nil {
+		return err
+	}
+	if h, err = ver.Hash(); err != nil {
+		return err
+	}
+	return ver.checkHash(h, want)
+}
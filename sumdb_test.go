@@ -0,0 +1,187 @@
+package modfs_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestSumDBSupported(t *testing.T) {
+	const key = "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ax18htTTAD8OuAn8"
+
+	supported := &countFS{FS: fstest.MapFS{
+		"sumdb/sum.golang.org/supported": &fstest.MapFile{},
+	}}
+	unsupported := &countFS{FS: fstest.MapFS{}}
+	errDown := errors.New("proxy down")
+
+	for _, tt := range []struct {
+		name string
+		fsys *countFS
+		want bool
+	}{
+		{"supported", supported, true},
+		{"unsupported", unsupported, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := modfs.NewSumDB(key, tt.fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if db.Name() != "sum.golang.org" {
+				t.Errorf("Name: got %q", db.Name())
+			}
+			for range 2 {
+				got, err := db.Supported()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("got %t, want %t", got, tt.want)
+				}
+			}
+			if tt.fsys.opens != 1 {
+				t.Errorf("proxy queried %d times, want 1", tt.fsys.opens)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		db, err := modfs.NewSumDB("sum.golang.org", errFS{errDown})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Supported(); !errors.Is(err, errDown) {
+			t.Errorf("got %v, want %v", err, errDown)
+		}
+	})
+
+	for _, key := range []string{"", "+033de0ae", "a/b+033de0ae", ".."} {
+		if _, err := modfs.NewSumDB(key, supported); err == nil {
+			t.Errorf("%q: no error", key)
+		}
+	}
+}
+
+func TestSkipSumDB(t *testing.T) {
+	m := modfs.New(fstest.MapFS{},
+		modfs.NoSumDB("*.corp.example.com,example.com/private"),
+		modfs.NoSumDB(""),
+		modfs.NoSumDB("github.com/acme/*"),
+	)
+	for path, want := range map[string]bool{
+		"golang.org/x/mod":              false,
+		"git.corp.example.com/team/lib": true,
+		"example.com/private":           true,
+		"example.com/private/sub":       true,
+		"example.com/privateer":         false,
+		"example.com/public":            false,
+		"github.com/acme/tool":          true,
+		"github.com/acme/tool/v2":       true,
+		"github.com/dolmen-go/modfs":    false,
+	} {
+		if got := m.SkipSumDB(path); got != want {
+			t.Errorf("SkipSumDB(%q): got %t, want %t", path, got, want)
+		}
+	}
+
+	if modfs.New(fstest.MapFS{}).SkipSumDB("example.com/private") {
+		t.Error("no patterns: got true")
+	}
+}
+
+// sumdbFS serves the checksum database name of proxy with handler.
+type sumdbFS struct {
+	proxy   fstest.MapFS
+	name    string
+	handler http.Handler
+}
+
+func (s *sumdbFS) Open(name string) (fs.File, error) {
+	p, ok := strings.CutPrefix(name, "sumdb/"+s.name+"/")
+	if !ok {
+		return s.proxy.Open(name)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+p, nil))
+	if rec.Code != http.StatusOK {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return fstest.MapFS{"f": &fstest.MapFile{Data: rec.Body.Bytes()}}.Open("f")
+}
+
+func TestVerifyWithSumDB(t *testing.T) {
+	const name = "sum.example.com"
+	signer, verifier, err := note.GenerateKey(rand.Reader, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The database records the hashes of the genuine modules
+	genuine := fstest.MapFS{}
+	for _, path := range []string{"example.com/a", "example.com/tampered", "example.com/private"} {
+		addModule(t, genuine, path, "v1.0.0", map[string]string{"a.go": "package a\n"})
+	}
+	gosum := func(path, vers string) ([]byte, error) {
+		if path == "example.com/private" {
+			return nil, fs.ErrNotExist
+		}
+		lines, err := openVersion(t, modfs.New(genuine), path, vers).SumLines()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.Join(lines, "\n") + "\n"), nil
+	}
+
+	proxy := &sumdbFS{
+		proxy:   fstest.MapFS{},
+		name:    name,
+		handler: sumdb.NewServer(sumdb.NewTestServer(signer, gosum)),
+	}
+	for path, f := range genuine {
+		proxy.proxy[path] = f
+	}
+	addModule(t, proxy.proxy, "example.com/tampered", "v1.0.0", map[string]string{"a.go": "package a // evil\n"})
+	m := modfs.New(proxy, modfs.NoSumDB("example.com/private"))
+
+	db, err := modfs.NewSumDB(verifier, proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := openVersion(t, m, "example.com/a", "v1.0.0").VerifyWithSumDB(db); err != nil {
+		t.Errorf("example.com/a: %v", err)
+	}
+	if err := openVersion(t, m, "example.com/tampered", "v1.0.0").VerifyWithSumDB(db); !errors.Is(err, modfs.ErrHashMismatch) {
+		t.Errorf("example.com/tampered: got %v, want ErrHashMismatch", err)
+	}
+	// Unknown to the database, but skipped
+	if err := openVersion(t, m, "example.com/private", "v1.0.0").VerifyWithSumDB(db); err != nil {
+		t.Errorf("example.com/private: %v", err)
+	}
+	if err := openVersion(t, modfs.New(proxy), "example.com/private", "v1.0.0").VerifyWithSumDB(db); err == nil {
+		t.Error("example.com/private without NoSumDB: no error")
+	}
+
+	// Records signed by another key are rejected
+	_, otherVerifier, err := note.GenerateKey(rand.Reader, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDB, err := modfs.NewSumDB(otherVerifier, proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := openVersion(t, m, "example.com/a", "v1.0.0").VerifyWithSumDB(otherDB); err == nil {
+		t.Error("wrong key: no error")
+	}
+}
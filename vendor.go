@@ -0,0 +1,245 @@
+package modfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dolmen-go/modfs/internal/dirents"
+)
+
+// vendorModulesTxt is the path of modules.txt in a [VendorFS].
+const vendorModulesTxt = "vendor/modules.txt"
+
+// VendorFS exposes the content of a set of modules with the layout of the
+// vendor directory built by "go mod vendor":
+//   - vendor/<module path>/... holds the files of each module (without the
+//     module@version/ prefix of the zip archive);
+//   - vendor/modules.txt lists the modules and their packages.
+//
+// Unlike "go mod vendor", which copies only the packages imported by the
+// main module, all the files of the modules are exposed and all their
+// packages are listed. Each module is marked as an explicit requirement.
+type VendorFS struct {
+	mounts     map[string]fs.FS // "vendor/<module path>" => module content
+	zips       []ZipFS
+	modulesTxt []byte
+}
+
+// NewVendorFS opens the zip archive of each version of versions, which is
+// the resolved dependency set of a main module (one version per module
+// path, as selected by the go command), and builds a [VendorFS].
+//
+// The VendorFS must be closed to release the archives.
+func NewVendorFS(versions []*Version) (*VendorFS, error) {
+	versions = slices.SortedFunc(slices.Values(versions), func(a, b *Version) int {
+		return strings.Compare(a.module.Path, b.module.Path)
+	})
+	v := &VendorFS{mounts: make(map[string]fs.FS, len(versions))}
+	var txt bytes.Buffer
+	for i, ver := range versions {
+		if i > 0 && versions[i-1].module.Path == ver.module.Path {
+			v.Close()
+			return nil, fmt.Errorf("%s: multiple versions: %s, %s", ver.module.Path, versions[i-1].Version, ver.Version)
+		}
+		if err := v.add(&txt, ver); err != nil {
+			v.Close()
+			return nil, fmt.Errorf("%s@%s: %w", ver.module.Path, ver.Version, err)
+		}
+	}
+	v.modulesTxt = txt.Bytes()
+	return v, nil
+}
+
+// add mounts the content of ver and appends its section of modules.txt to
+// txt.
+func (v *VendorFS) add(txt *bytes.Buffer, ver *Version) error {
+	goVersion, err := ver.goDirective()
+	if err != nil {
+		return err
+	}
+	zfs, err := ver.OpenFS()
+	if err != nil {
+		return err
+	}
+	v.zips = append(v.zips, zfs)
+	v.mounts["vendor/"+ver.module.Path] = zfs
+
+	pkgs, err := findPackages(zfs, ver.module.Path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(txt, "# %s %s\n", ver.module.Path, ver.Version)
+	if goVersion != "" {
+		fmt.Fprintf(txt, "## explicit; go %s\n", goVersion)
+	} else {
+		txt.WriteString("## explicit\n")
+	}
+	for _, pkg := range slices.Sorted(maps.Keys(pkgs)) {
+		txt.WriteString(pkg + "\n")
+	}
+	return nil
+}
+
+// Close closes the zip archives of the modules.
+func (v *VendorFS) Close() error {
+	var errs []error
+	for _, zfs := range v.zips {
+		errs = append(errs, zfs.Close())
+	}
+	v.zips = nil
+	return errors.Join(errs...)
+}
+
+// mount returns the FS of the module containing name and the path of name
+// in that FS. Nested modules are resolved to the innermost module.
+func (v *VendorFS) mount(name string) (fs.FS, string, bool) {
+	for dir := name; strings.HasPrefix(dir, "vendor/"); dir = dir[:strings.LastIndexByte(dir, '/')] {
+		if fsys, ok := v.mounts[dir]; ok {
+			if dir == name {
+				return fsys, ".", true
+			}
+			return fsys, name[len(dir)+1:], true
+		}
+	}
+	return nil, "", false
+}
+
+// virtualEntries returns the entries of directory name which come from the
+// layout (directories leading to modules, modules.txt) instead of the
+// content of a module.
+func (v *VendorFS) virtualEntries(name string) []fs.DirEntry {
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	entries := make(map[string]fs.DirEntry)
+	for p := range v.mounts {
+		if rest, ok := strings.CutPrefix(p, prefix); ok && rest != "" {
+			child, _, _ := strings.Cut(rest, "/")
+			entries[child] = &vendorInfo{name: child, dir: true}
+		}
+	}
+	if strings.HasPrefix(vendorModulesTxt, prefix) {
+		child, rest, _ := strings.Cut(vendorModulesTxt[len(prefix):], "/")
+		if rest != "" {
+			entries[child] = &vendorInfo{name: child, dir: true}
+		} else {
+			entries[child] = &vendorInfo{name: child, size: int64(len(v.modulesTxt))}
+		}
+	}
+	return slices.Collect(maps.Values(entries))
+}
+
+// Open implements [fs.FS].
+func (v *VendorFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == vendorModulesTxt {
+		return &vendorFile{
+			Reader: bytes.NewReader(v.modulesTxt),
+			info:   vendorInfo{name: "modules.txt", size: int64(len(v.modulesTxt))},
+		}, nil
+	}
+
+	virtual := v.virtualEntries(name)
+	if fsys, rel, ok := v.mount(name); ok {
+		f, err := fsys.Open(rel)
+		if err != nil {
+			if len(virtual) > 0 {
+				// Directory leading to a nested module
+				return newVendorDir(name, nil, virtual), nil
+			}
+			var pe *fs.PathError
+			if errors.As(err, &pe) {
+				pe.Path = name
+			}
+			return nil, err
+		}
+		if len(virtual) == 0 && rel != "." {
+			return f, nil
+		}
+		// Root of a module (named after the zip prefix in fsys) or directory
+		// of a module which contains a nested module
+		defer f.Close()
+		entries, err := fs.ReadDir(fsys, rel)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return newVendorDir(name, entries, virtual), nil
+	}
+	if len(virtual) == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return newVendorDir(name, nil, virtual), nil
+}
+
+// vendorInfo implements [fs.FileInfo] and [fs.DirEntry] for the files and
+// directories of the layout of a [VendorFS].
+type vendorInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i *vendorInfo) Name() string { return i.name }
+func (i *vendorInfo) Size() int64  { return i.size }
+func (i *vendorInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+func (i *vendorInfo) ModTime() time.Time         { return time.Time{} }
+func (i *vendorInfo) IsDir() bool                { return i.dir }
+func (i *vendorInfo) Sys() any                   { return nil }
+func (i *vendorInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i *vendorInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// vendorFile is vendor/modules.txt.
+type vendorFile struct {
+	*bytes.Reader
+	info vendorInfo
+}
+
+func (f *vendorFile) Stat() (fs.FileInfo, error) { return &f.info, nil }
+func (f *vendorFile) Close() error               { return nil }
+
+// vendorDir implements [fs.ReadDirFile] for directories of a [VendorFS]
+// which have entries from the layout.
+type vendorDir struct {
+	path    string
+	info    vendorInfo
+	entries dirents.Entries
+}
+
+// newVendorDir merges the entries of a directory of a module with the
+// virtual entries.
+func newVendorDir(name string, entries, virtual []fs.DirEntry) *vendorDir {
+	for _, e := range virtual {
+		if !slices.ContainsFunc(entries, func(d fs.DirEntry) bool { return d.Name() == e.Name() }) {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	base := name[strings.LastIndexByte(name, '/')+1:]
+	return &vendorDir{path: name, info: vendorInfo{name: base, dir: true}, entries: entries}
+}
+
+func (d *vendorDir) Stat() (fs.FileInfo, error) { return &d.info, nil }
+func (d *vendorDir) Close() error               { return nil }
+
+func (d *vendorDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errors.New("is a directory")}
+}
+
+func (d *vendorDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	return d.entries.ReadDir(n)
+}
@@ -0,0 +1,103 @@
+package modfs_test
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func TestVendorFS(t *testing.T) {
+	proxy := fstest.MapFS{}
+	addModule(t, proxy, "example.com/b", "v1.2.0", map[string]string{
+		"go.mod":          "module example.com/b\n\ngo 1.21\n",
+		"b.go":            "package b\n",
+		"internal/i/i.go": "package i\n",
+		"LICENSE":         "license b\n",
+	})
+	addModule(t, proxy, "example.com/a", "v1.0.0", map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n",
+	})
+	addModule(t, proxy, "example.com/a/sub", "v0.1.0", map[string]string{
+		"go.mod": "module example.com/a/sub\n\ngo 1.22\n",
+		"sub.go": "package sub\n",
+	})
+	m := modfs.New(proxy)
+
+	vfs, err := modfs.NewVendorFS([]*modfs.Version{
+		openVersion(t, m, "example.com/b", "v1.2.0"),
+		openVersion(t, m, "example.com/a/sub", "v0.1.0"),
+		openVersion(t, m, "example.com/a", "v1.0.0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vfs.Close()
+
+	const wantModulesTxt = "" +
+		"# example.com/a v1.0.0\n" +
+		"## explicit\n" +
+		"example.com/a\n" +
+		"# example.com/a/sub v0.1.0\n" +
+		"## explicit; go 1.22\n" +
+		"example.com/a/sub\n" +
+		"# example.com/b v1.2.0\n" +
+		"## explicit; go 1.21\n" +
+		"example.com/b\n" +
+		"example.com/b/internal/i\n"
+	b, err := fs.ReadFile(vfs, "vendor/modules.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != wantModulesTxt {
+		t.Errorf("modules.txt:\n%s\nwant:\n%s", b, wantModulesTxt)
+	}
+
+	b, err = fs.ReadFile(vfs, "vendor/example.com/b/internal/i/i.go")
+	if err != nil || string(b) != "package i\n" {
+		t.Errorf("i.go: got %q, %v", b, err)
+	}
+
+	// The nested module appears in the directory of the outer module
+	entries, err := fs.ReadDir(vfs, "vendor/example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got, want := names, []string{"a.go", "go.mod", "sub"}; !slices.Equal(got, want) {
+		t.Errorf("vendor/example.com/a: got %q, want %q", got, want)
+	}
+
+	if err := fstest.TestFS(vfs,
+		"vendor/modules.txt",
+		"vendor/example.com/a/a.go",
+		"vendor/example.com/a/sub/sub.go",
+		"vendor/example.com/b/LICENSE",
+		"vendor/example.com/b/internal/i/i.go",
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVendorFSDuplicate(t *testing.T) {
+	proxy := fstest.MapFS{}
+	files := map[string]string{"go.mod": "module example.com/dup\n"}
+	addModule(t, proxy, "example.com/dup", "v1.0.0", files)
+	addModule(t, proxy, "example.com/dup", "v1.1.0", files)
+	m := modfs.New(proxy)
+
+	_, err := modfs.NewVendorFS([]*modfs.Version{
+		openVersion(t, m, "example.com/dup", "v1.0.0"),
+		openVersion(t, m, "example.com/dup", "v1.1.0"),
+	})
+	if err == nil {
+		t.Fatal("no error")
+	}
+	t.Log(err)
+}
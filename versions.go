@@ -0,0 +1,221 @@
+package modfs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// scanVersions calls fn for each version listed in @v/list.
+//
+// @v/list is a plain text file with one version per line.
+func (m *Module) scanVersions(ctx context.Context, fn func(version string)) error {
+	const path = "@v/list"
+	f, err := m.fs.open(ctx, m.file(path))
+	if err != nil {
+		return fmt.Errorf("%s/%s: %w", m.Path, path, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		v := strings.TrimSpace(sc.Text()) // Also handles CRLF
+		if v != "" {
+			fn(v)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s/%s: %w", m.Path, path, err)
+	}
+	return nil
+}
+
+// VersionCount returns the number of versions listed by the proxy, without
+// keeping the list in memory. Blank lines are not counted, but the versions
+// are not validated.
+func (m *Module) VersionCount() (int, error) {
+	n := 0
+	if err := m.scanVersions(context.Background(), func(string) { n++ }); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ListVersions returns the versions listed by the proxy, sorted in
+// ascending semver order. Only the Version field of each [VersionInfo]
+// is set.
+//
+// Invalid semantic versions are ignored.
+func (m *Module) ListVersions() ([]*VersionInfo, error) {
+	return m.ListVersionsContext(context.Background())
+}
+
+// ListVersionsContext is like [Module.ListVersions] with a context.
+func (m *Module) ListVersionsContext(ctx context.Context) ([]*VersionInfo, error) {
+	var list []string
+	err := m.scanVersions(ctx, func(v string) {
+		if semver.IsValid(v) {
+			list = append(list, v)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	semver.Sort(list)
+	list = slices.Compact(list)
+
+	versions := make([]*VersionInfo, len(list))
+	for i, v := range list {
+		versions[i] = &VersionInfo{Version: v}
+	}
+	return versions, nil
+}
+
+// ListVersionsFiltered is like [Module.ListVersions], but versions retracted
+// by the module are excluded. [Module.ListVersions] returns the raw list.
+//
+// Like the go command, the retract directives are read from the go.mod of
+// the latest version listed: the highest release, or the highest
+// pre-release if the module has no release. Both single versions and
+// version intervals ("retract [v1.0.0, v1.2.0]") are handled.
+func (m *Module) ListVersionsFiltered() ([]*VersionInfo, error) {
+	versions, err := m.ListVersions()
+	if err != nil || len(versions) == 0 {
+		return versions, err
+	}
+
+	latest := versions[len(versions)-1]
+	for _, v := range slices.Backward(versions) {
+		if semver.Prerelease(v.Version) == "" {
+			latest = v
+			break
+		}
+	}
+	ver, err := m.version(latest.Version)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ver.parseGoMod()
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Retract) == 0 {
+		return versions, nil
+	}
+	return slices.DeleteFunc(versions, func(v *VersionInfo) bool {
+		return slices.ContainsFunc(f.Retract, func(r *modfile.Retract) bool {
+			return semver.Compare(v.Version, r.Low) >= 0 && semver.Compare(v.Version, r.High) <= 0
+		})
+	}), nil
+}
+
+// ListVersionsLimit returns at most the n highest versions (in semver order)
+// listed by the proxy, sorted in ascending order.
+//
+// Only n versions are kept in memory while the list is read.
+// Invalid semantic versions are ignored.
+func (m *Module) ListVersionsLimit(n int) ([]*VersionInfo, error) {
+	return m.listVersionsLimit(context.Background(), n)
+}
+
+func (m *Module) listVersionsLimit(ctx context.Context, n int) ([]*VersionInfo, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	top := make([]string, 0, n) // sorted ascending
+	err := m.scanVersions(ctx, func(v string) {
+		if !semver.IsValid(v) {
+			return
+		}
+		if len(top) == n && semver.Compare(v, top[0]) <= 0 {
+			return
+		}
+		i, found := slices.BinarySearchFunc(top, v, semver.Compare)
+		if found {
+			return
+		}
+		if len(top) < n {
+			top = slices.Insert(top, i, v)
+		} else {
+			// Drop the lowest version
+			copy(top, top[1:i])
+			top[i-1] = v
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*VersionInfo, len(top))
+	for i, v := range top {
+		versions[i] = &VersionInfo{Version: v}
+	}
+	return versions, nil
+}
+
+// Versions returns the versions listed by the proxy which are valid for the
+// module path, sorted in ascending semver order.
+//
+// Versions must match the major version suffix of the module path: for
+// "example.com/mod/v2" only v2 versions are kept; for "example.com/mod", only
+// v0, v1 and "+incompatible" versions. Invalid versions are ignored.
+//
+// The .info of each version is not fetched: only the Version field of the
+// VersionInfo is set.
+func (m *Module) Versions() ([]*Version, error) {
+	_, pathMajor, ok := module.SplitPathVersion(m.Path)
+	if !ok {
+		return nil, fmt.Errorf("%s: invalid module path", m.Path)
+	}
+	var list []string
+	err := m.scanVersions(context.Background(), func(v string) {
+		if semver.Canonical(v) == strings.TrimSuffix(v, "+incompatible") && module.CheckPathMajor(v, pathMajor) == nil {
+			list = append(list, v)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	semver.Sort(list)
+	list = slices.Compact(list)
+
+	versions := make([]*Version, 0, len(list))
+	for _, v := range list {
+		escVersion, err := module.EscapeVersion(v)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, &Version{
+			module:      m,
+			escVersion:  escVersion,
+			VersionInfo: VersionInfo{Version: v},
+		})
+	}
+	return versions, nil
+}
+
+// LatestRelease returns the highest release of the module listed by the
+// proxy: pre-releases and pseudo-versions are skipped. Unlike
+// [Module.VersionLatest], this doesn't depend on the @latest endpoint, but
+// retracted versions are not excluded.
+//
+// If the module has no release, the error wraps [fs.ErrNotExist].
+func (m *Module) LatestRelease() (*Version, error) {
+	versions, err := m.Versions()
+	if err != nil {
+		return nil, err
+	}
+	for _, ver := range slices.Backward(versions) {
+		if semver.Prerelease(ver.Version) == "" && !module.IsPseudoVersion(ver.Version) {
+			return m.Version(ver.Version)
+		}
+	}
+	return nil, fmt.Errorf("%s: no release: %w", m.Path, fs.ErrNotExist)
+}
@@ -0,0 +1,194 @@
+package modfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dolmen-go/modfs"
+)
+
+func versionStrings(versions []*modfs.VersionInfo) []string {
+	s := make([]string, len(versions))
+	for i, v := range versions {
+		s[i] = v.Version
+	}
+	return s
+}
+
+func TestListVersions(t *testing.T) {
+	proxy := fstest.MapFS{
+		"example.com/many/@latest": &fstest.MapFile{Data: []byte(`{"Version":"v1.10.0","Time":"2025-01-01T00:00:00Z"}`)},
+		// Not sorted, with CRLF, blank lines, duplicates and invalid versions
+		"example.com/many/@v/list": &fstest.MapFile{Data: []byte("v1.2.0\r\nv1.10.0\nv0.1.0\n\nv1.9.0\nbad\nv1.10.0-rc.1\nv1.2.0\n  \nv1.3.0")},
+	}
+	mod, err := modfs.New(proxy).OpenModule("example.com/many")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := mod.ListVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"v0.1.0", "v1.2.0", "v1.3.0", "v1.9.0", "v1.10.0-rc.1", "v1.10.0"}
+	if got := versionStrings(versions); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestListVersionsFiltered(t *testing.T) {
+	proxy := fstest.MapFS{
+		"example.com/retract/@latest": &fstest.MapFile{Data: []byte(`{"Version":"v1.3.0","Time":"2025-01-01T00:00:00Z"}`)},
+		"example.com/retract/@v/list": &fstest.MapFile{Data: []byte("v1.0.0\nv1.1.0\nv1.1.1\nv1.2.0\nv1.2.1\nv1.3.0\nv1.4.0-rc.1\n")},
+		// Retractions are read from the highest release, not the pre-release
+		"example.com/retract/@v/v1.3.0.mod": &fstest.MapFile{Data: []byte(`module example.com/retract
+
+retract (
+	v1.0.0 // Broken
+	[v1.1.0, v1.2.0] // Security issue
+)
+`)},
+		"example.com/retract/@v/v1.4.0-rc.1.mod": &fstest.MapFile{Data: []byte("module example.com/retract\n\nretract v1.3.0\n")},
+	}
+	mod, err := modfs.New(proxy).OpenModule("example.com/retract")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := mod.ListVersionsFiltered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"v1.2.1", "v1.3.0", "v1.4.0-rc.1"}
+	if got := versionStrings(versions); !slices.Equal(got, want) {
+		t.Errorf("ListVersionsFiltered: got %q, want %q", got, want)
+	}
+
+	versions, err = mod.ListVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 7 {
+		t.Errorf("ListVersions: got %q", versionStrings(versions))
+	}
+}
+
+func TestListVersionsLimit(t *testing.T) {
+	proxy := fstest.MapFS{
+		"example.com/many/@latest": &fstest.MapFile{Data: []byte(`{"Version":"v1.10.0","Time":"2025-01-01T00:00:00Z"}`)},
+		// Not sorted, with CRLF and blank lines
+		"example.com/many/@v/list": &fstest.MapFile{Data: []byte("v1.2.0\r\nv1.10.0\nv0.1.0\n\nv1.9.0\nv1.10.0-rc.1\nv1.3.0\n")},
+	}
+	mod, err := modfs.New(proxy).OpenModule("example.com/many")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, want := range map[int][]string{
+		0:  nil,
+		1:  {"v1.10.0"},
+		3:  {"v1.9.0", "v1.10.0-rc.1", "v1.10.0"},
+		10: {"v0.1.0", "v1.2.0", "v1.3.0", "v1.9.0", "v1.10.0-rc.1", "v1.10.0"},
+	} {
+		versions, err := mod.ListVersionsLimit(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := versionStrings(versions); !slices.Equal(got, want) {
+			t.Errorf("ListVersionsLimit(%d): got %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestVersionCount(t *testing.T) {
+	for list, want := range map[string]int{
+		"":                                  0,
+		"\n":                                0,
+		"v1.0.0":                            1,
+		"v1.0.0\n":                          1,
+		"v1.0.0\r\nv1.1.0\r\n":              2,
+		"v1.0.0\nv1.1.0\n\nv1.2.0-rc.1\n\n": 3,
+	} {
+		proxy := fstest.MapFS{
+			"example.com/count/@v/list": &fstest.MapFile{Data: []byte(list)},
+		}
+		mod, err := modfs.New(proxy).OpenModule("example.com/count")
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := mod.VersionCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("%q: got %d, want %d", list, n, want)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	proxy := fstest.MapFS{}
+	for _, v := range []string{"v1.2.0", "v0.1.0", "v2.0.0+incompatible", "v1.10.0", "v1.11.0-rc.1", "v1.10.1-0.20250101000000-0123456789ab"} {
+		addModule(t, proxy, "example.com/mod", v, nil)
+	}
+	for _, v := range []string{"v2.1.0", "v2.0.0", "v3.0.0"} {
+		addModule(t, proxy, "example.com/mod/v2", v, nil)
+	}
+	addModule(t, proxy, "example.com/pre", "v0.1.0-alpha", nil)
+	// Invalid entries
+	proxy["example.com/mod/@v/list"].Data = append(proxy["example.com/mod/@v/list"].Data, "latest\nv1\n"...)
+	m := modfs.New(proxy)
+
+	for path, want := range map[string]struct {
+		versions []string
+		release  string
+	}{
+		"example.com/mod": {
+			[]string{"v0.1.0", "v1.2.0", "v1.10.0", "v1.10.1-0.20250101000000-0123456789ab", "v1.11.0-rc.1", "v2.0.0+incompatible"},
+			"v2.0.0+incompatible",
+		},
+		"example.com/mod/v2": {
+			[]string{"v2.0.0", "v2.1.0"},
+			"v2.1.0",
+		},
+		"example.com/pre": {
+			[]string{"v0.1.0-alpha"},
+			"",
+		},
+	} {
+		mod, err := m.OpenModule(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err := mod.Versions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]string, len(versions))
+		for i, v := range versions {
+			got[i] = v.Version
+		}
+		if !slices.Equal(got, want.versions) {
+			t.Errorf("%s: Versions: got %q, want %q", path, got, want.versions)
+		}
+
+		ver, err := mod.LatestRelease()
+		if want.release == "" {
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s: LatestRelease: got %v, want fs.ErrNotExist", path, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: LatestRelease: %v", path, err)
+		}
+		if ver.Version != want.release {
+			t.Errorf("%s: LatestRelease: got %q, want %q", path, ver.Version, want.release)
+		}
+		if ver.Time.IsZero() {
+			t.Errorf("%s: LatestRelease: missing Time", path)
+		}
+	}
+}
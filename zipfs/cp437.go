@@ -0,0 +1,55 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"strings"
+)
+
+// cp437 maps the bytes 0x80-0xFF of code page 437 (the original IBM PC
+// character set, used by default for names of zip entries) to runes. Bytes
+// below 0x80 are ASCII. This is the same mapping as
+// golang.org/x/text/encoding/charmap.CodePage437.
+var cp437 = [128]rune{
+	'Ç', 'ü', 'é', 'â', 'ä', 'à', 'å', 'ç',
+	'ê', 'ë', 'è', 'ï', 'î', 'ì', 'Ä', 'Å',
+	'É', 'æ', 'Æ', 'ô', 'ö', 'ò', 'û', 'ù',
+	'ÿ', 'Ö', 'Ü', '¢', '£', '¥', '₧', 'ƒ',
+	'á', 'í', 'ó', 'ú', 'ñ', 'Ñ', 'ª', 'º',
+	'¿', '⌐', '¬', '½', '¼', '¡', '«', '»',
+	'░', '▒', '▓', '│', '┤', '╡', '╢', '╖',
+	'╕', '╣', '║', '╗', '╝', '╜', '╛', '┐',
+	'└', '┴', '┬', '├', '─', '┼', '╞', '╟',
+	'╚', '╔', '╩', '╦', '╠', '═', '╬', '╧',
+	'╨', '╤', '╥', '╙', '╘', '╒', '╓', '╫',
+	'╪', '┘', '┌', '█', '▄', '▌', '▐', '▀',
+	'α', 'ß', 'Γ', 'π', 'Σ', 'σ', 'µ', 'τ',
+	'Φ', 'Θ', 'Ω', 'δ', '∞', 'φ', 'ε', '∩',
+	'≡', '±', '≥', '≤', '⌠', '⌡', '÷', '≈',
+	'°', '∙', '·', '√', 'ⁿ', '²', '■', '\u00a0',
+}
+
+// decodeCP437 decodes s from code page 437.
+func decodeCP437(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x80 {
+			b.WriteByte(c)
+		} else {
+			b.WriteRune(cp437[c-0x80])
+		}
+	}
+	return b.String()
+}
+
+// decodeName returns f, or a copy of f with the name decoded from code page
+// 437 if the name isn't encoded in UTF-8 (see [zip.FileHeader].NonUTF8).
+func decodeName(f *zip.File) *zip.File {
+	if !f.NonUTF8 {
+		return f
+	}
+	decoded := *f
+	decoded.Name = decodeCP437(f.Name)
+	decoded.NonUTF8 = false
+	return &decoded
+}
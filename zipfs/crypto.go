@@ -0,0 +1,178 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ErrEncrypted is returned when opening an encrypted entry of the archive
+// without [Options].Password (or [Options].Decrypter for encryption methods
+// other than ZipCrypto).
+var ErrEncrypted = errors.New("encrypted entry")
+
+// ErrPassword is returned when opening an entry encrypted with ZipCrypto
+// with a wrong [Options].Password.
+var ErrPassword = errors.New("invalid password")
+
+// Decrypter decrypts entries encrypted with methods not implemented by
+// [ZipFS], such as WinZip AES (compression method 99, with the actual
+// compression method in the 0x9901 extra field). The data of the entry is
+// available from [zip.File.OpenRaw].
+type Decrypter interface {
+	// Open returns the decrypted and decompressed content of f.
+	Open(f *zip.File, password string) (io.ReadCloser, error)
+}
+
+const (
+	flagEncrypted       = 0x1
+	flagDataDescriptor  = 0x8
+	flagStrongEncrypted = 0x40
+	methodAES           = 99
+)
+
+func isEncrypted(f *zip.File) bool {
+	return f.Flags&flagEncrypted != 0
+}
+
+// isZipCrypto reports whether f is encrypted with the traditional PKWARE
+// encryption (ZipCrypto).
+func isZipCrypto(f *zip.File) bool {
+	return isEncrypted(f) && f.Flags&flagStrongEncrypted == 0 && f.Method != methodAES
+}
+
+// openEncrypted opens the content of the encrypted entry f.
+func (z *ZipFS) openEncrypted(f *zip.File) (io.ReadCloser, error) {
+	if !isZipCrypto(f) {
+		if z.opts.Decrypter == nil {
+			return nil, ErrEncrypted
+		}
+		return z.opts.Decrypter.Open(f, z.opts.Password)
+	}
+	if z.opts.Password == "" {
+		return nil, ErrEncrypted
+	}
+
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	keys := newZipCryptoKeys(z.opts.Password)
+	var header [zipCryptoHeaderSize]byte
+	if _, err := io.ReadFull(raw, header[:]); err != nil {
+		return nil, err
+	}
+	keys.decrypt(header[:])
+	// The last byte of the header allows to check the password
+	check := byte(f.CRC32 >> 24)
+	if f.Flags&flagDataDescriptor != 0 {
+		check = byte(f.ModifiedTime >> 8)
+	}
+	if header[zipCryptoHeaderSize-1] != check {
+		return nil, ErrPassword
+	}
+
+	var r io.Reader = &zipCryptoReader{r: raw, keys: keys}
+	var rc io.ReadCloser
+	switch f.Method {
+	case zip.Store:
+		rc = io.NopCloser(r)
+	case zip.Deflate:
+		rc = flate.NewReader(r)
+	default:
+		return nil, zip.ErrAlgorithm
+	}
+	return &checksumReader{rc: rc, hash: crc32.NewIEEE(), want: f.CRC32, size: f.UncompressedSize64}, nil
+}
+
+// zipCryptoHeaderSize is the size of the encryption header which precedes
+// the data of entries encrypted with ZipCrypto.
+const zipCryptoHeaderSize = 12
+
+// zipCryptoKeys is the state of the traditional PKWARE encryption
+// (ZipCrypto), as described in section 6.1 of the .ZIP File Format
+// Specification (APPNOTE.TXT).
+//
+// ZipCrypto is weak: the password can be recovered from a few bytes of
+// known content (known-plaintext attack). It only protects against casual
+// access.
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password string) *zipCryptoKeys {
+	keys := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for i := 0; i < len(password); i++ {
+		keys.update(password[i])
+	}
+	return keys
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ crc>>8
+}
+
+func (k *zipCryptoKeys) update(b byte) {
+	k[0] = crc32Update(k[0], b)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+func (k *zipCryptoKeys) streamByte() byte {
+	t := uint16(k[2] | 2)
+	return byte((t * (t ^ 1)) >> 8)
+}
+
+// decrypt decrypts b in place.
+func (k *zipCryptoKeys) decrypt(b []byte) {
+	for i, c := range b {
+		b[i] = c ^ k.streamByte()
+		k.update(b[i])
+	}
+}
+
+// zipCryptoReader decrypts the data read from r.
+type zipCryptoReader struct {
+	r    io.Reader
+	keys *zipCryptoKeys
+}
+
+func (r *zipCryptoReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.keys.decrypt(b[:n])
+	return n, err
+}
+
+// checksumReader checks the size and the CRC-32 of the content read from
+// rc, like the readers of [zip.File.Open]. This detects wrong passwords
+// that pass the check of the encryption header (1 chance out of 256).
+type checksumReader struct {
+	rc   io.ReadCloser
+	hash hash.Hash32
+	want uint32
+	size uint64
+	read uint64
+}
+
+func (r *checksumReader) Read(b []byte) (int, error) {
+	n, err := r.rc.Read(b)
+	r.hash.Write(b[:n])
+	r.read += uint64(n)
+	if r.read > r.size {
+		return n, zip.ErrFormat
+	}
+	if err == io.EOF {
+		if r.read != r.size {
+			return n, io.ErrUnexpectedEOF
+		}
+		if r.hash.Sum32() != r.want {
+			return n, zip.ErrChecksum
+		}
+	}
+	return n, err
+}
+
+func (r *checksumReader) Close() error {
+	return r.rc.Close()
+}
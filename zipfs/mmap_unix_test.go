@@ -0,0 +1,131 @@
+//go:build unix
+
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// writeLargeZip writes an archive of n stored files of the given size.
+func writeLargeZip(tb testing.TB, n, size int) string {
+	tb.Helper()
+	name := filepath.Join(tb.TempDir(), "large.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	data := make([]byte, size)
+	rnd := rand.NewChaCha8([32]byte{})
+	for i := range n {
+		rnd.Read(data)
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: "data/" + strconv.Itoa(i) + ".bin", Method: zip.Store})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		fw.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return name
+}
+
+// mmap maps the file name in memory. The returned function unmaps it.
+func mmap(tb testing.TB, name string) ([]byte, func()) {
+	tb.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		tb.Skip("mmap:", err)
+	}
+	return data, func() { syscall.Munmap(data) }
+}
+
+func readAllFiles(tb testing.TB, zipFS *ZipFS) int64 {
+	var total int64
+	err := fs.WalkDir(zipFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := zipFS.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		n, err := io.Copy(io.Discard, f)
+		total += n
+		return err
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return total
+}
+
+func TestNewFromReaderAtMmap(t *testing.T) {
+	name := writeLargeZip(t, 4, 100<<10)
+	data, unmap := mmap(t, name)
+	defer unmap()
+
+	zipFS, err := NewFromReaderAt(bytes.NewReader(data), int64(len(data)), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := readAllFiles(t, zipFS); n != 4*100<<10 {
+		t.Errorf("read %d bytes", n)
+	}
+}
+
+func BenchmarkReaderAt(b *testing.B) {
+	const n, size = 64, 256 << 10
+	name := writeLargeZip(b, n, size)
+
+	b.Run("file", func(b *testing.B) {
+		f, err := os.Open(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer f.Close()
+		fi, _ := f.Stat()
+		b.SetBytes(n * size)
+		for b.Loop() {
+			zipFS, err := NewFromReaderAt(f, fi.Size(), Options{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			readAllFiles(b, zipFS)
+		}
+	})
+
+	b.Run("mmap", func(b *testing.B) {
+		data, unmap := mmap(b, name)
+		defer unmap()
+		b.SetBytes(n * size)
+		for b.Loop() {
+			zipFS, err := NewFromReaderAt(bytes.NewReader(data), int64(len(data)), Options{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			readAllFiles(b, zipFS)
+		}
+	})
+}
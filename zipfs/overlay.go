@@ -0,0 +1,131 @@
+package zipfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/dolmen-go/modfs/internal/dirents"
+)
+
+// Overlay returns a union view of upper on top of the archive: the files of
+// upper shadow the entries of the archive with the same name, and the
+// listings of the directories present in both are merged. This allows to
+// patch the content of an archive (such as the go.mod of a module) without
+// rebuilding it:
+//
+//	patched := z.Overlay(fstest.MapFS{
+//		"go.mod": &fstest.MapFile{Data: goMod},
+//	})
+//
+// A file of upper doesn't hide the entries of the archive below a
+// directory with the same name.
+func (z *ZipFS) Overlay(upper fs.FS) fs.FS {
+	return &overlayFS{upper: upper, lower: z}
+}
+
+// overlayFS implements [fs.FS], [fs.ReadDirFS], [fs.ReadFileFS] and
+// [fs.StatFS] for [ZipFS.Overlay].
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := o.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.lower.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return f, nil
+	}
+	// The listing is merged with the one of the archive
+	return &overlayDir{File: f, o: o, name: path.Clean(name)}, nil
+}
+
+func (o *overlayFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	b, err := fs.ReadFile(o.upper, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.ReadFile(o.lower, name)
+	}
+	return b, err
+}
+
+func (o *overlayFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	fi, err := fs.Stat(o.upper, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.Stat(o.lower, name)
+	}
+	return fi, err
+}
+
+// ReadDir implements [fs.ReadDirFS]. The entries of upper replace the
+// entries of the archive with the same name. Entries are sorted by name.
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	upper, err := fs.ReadDir(o.upper, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.ReadDir(o.lower, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	lower, err := fs.ReadDir(o.lower, name)
+	if err != nil {
+		// Not in the archive, or not a directory there: upper wins
+		return upper, nil
+	}
+
+	entries := slices.Clone(upper)
+	for _, e := range lower {
+		if _, found := slices.BinarySearchFunc(upper, e.Name(), func(e fs.DirEntry, name string) int {
+			return strings.Compare(e.Name(), name)
+		}); !found {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// overlayDir implements [fs.ReadDirFile] for the directories of upper: the
+// merged listing is loaded by the first call to ReadDir.
+type overlayDir struct {
+	fs.File
+	o       *overlayFS
+	name    string
+	entries dirents.Entries
+	loaded  bool
+}
+
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		entries, err := d.o.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.loaded = entries, true
+	}
+	return d.entries.ReadDir(n)
+}
@@ -0,0 +1,75 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// Verify reads the content of the file name fully and checks its size and
+// its CRC-32 against the header of the entry. On mismatch, the error wraps
+// [zip.ErrChecksum] and reports both checksums.
+//
+// [archive/zip] checks the CRC-32 only when an entry is read until EOF:
+// Verify allows to detect a corrupted archive (such as a truncated or
+// altered download) before serving partial reads from it.
+//
+// Entries encrypted with AES are authenticated by the [Decrypter] instead.
+// The limits of [Options] apply.
+func (z *ZipFS) Verify(name string) error {
+	z.index()
+
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "verify", Path: name, Err: fs.ErrInvalid}
+	}
+	file, ok := z.files[path.Clean(name)]
+	if !ok {
+		if _, ok := z.dirs[path.Clean(name)]; ok {
+			return &fs.PathError{Op: "verify", Path: name, Err: errors.New("is a directory")}
+		}
+		return &fs.PathError{Op: "verify", Path: name, Err: fs.ErrNotExist}
+	}
+	if err := z.verify(file); err != nil {
+		return &fs.PathError{Op: "verify", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (z *ZipFS) verify(f *zip.File) error {
+	rc, err := z.openFile(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	hash := crc32.NewIEEE()
+	// A checksum error of the reader is reported below with more details
+	if _, err := io.Copy(hash, rc); err != nil && !errors.Is(err, zip.ErrChecksum) {
+		return err
+	}
+	if isEncrypted(f) && !isZipCrypto(f) {
+		// The CRC-32 is not stored (AE-2) or is checked by the Decrypter
+		return nil
+	}
+	if got := hash.Sum32(); got != f.CRC32 {
+		return fmt.Errorf("%w: got %08x, want %08x", zip.ErrChecksum, got, f.CRC32)
+	}
+	return nil
+}
+
+// VerifyAll runs [ZipFS.Verify] on each file of the archive (see
+// [ZipFS.Files]) and returns the errors joined with [errors.Join], so all the
+// corrupted files are reported.
+func (z *ZipFS) VerifyAll() error {
+	var errs []error
+	for name, entry := range z.Files() {
+		if err := z.verify(entry.(*fileEntry).file); err != nil {
+			errs = append(errs, &fs.PathError{Op: "verify", Path: name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
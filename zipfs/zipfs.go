@@ -2,53 +2,258 @@ package zipfs
 
 import (
 	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"io"
 	"io/fs"
+	"iter"
+	"maps"
+	"os"
 	"path"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// ZipFS implements [io/fs.ReadFileFS], [io/fs.SubFS], and [io/fs.ReadDirFS] interfaces
+// ZipFS implements [io/fs.ReadFileFS], [io/fs.SubFS], [io/fs.ReadDirFS], [io/fs.GlobFS] and [io/fs.StatFS] interfaces
 // for a zip archive. It provides a read-only filesystem interface to access files and
 // directories within the zip archive.
+//
+// If the archive has multiple entries with the same name, the last one wins,
+// as on extraction.
+//
+// A ZipFS is safe for concurrent use by multiple goroutines, in both eager
+// and lazy ([Options].LazyIndex) modes: the index is not modified once built,
+// each file opened reads the archive through its own decompressor, and the
+// counter of [Options].MaxTotalSize is atomic. The files returned by Open are
+// not safe for concurrent use, but distinct files (even of the same entry)
+// can be used concurrently.
 type ZipFS struct {
 	reader *zip.Reader
+	opts   Options
+	once   sync.Once            // builds the index
+	read   atomic.Int64         // bytes read, for MaxTotalSize
 	files  map[string]*zip.File // direct file lookup
 	dirs   map[string]*dirInfo  // emulated directory entries
+	closer io.Closer            // file opened by OpenFile
+
+	rejected []*zip.File // entries with unsafe paths
+	list     []fileItem  // files, in the order of the archive
+}
+
+// fileItem is a file of the index of a [ZipFS], for [ZipFS.Files].
+type fileItem struct {
+	name  string
+	entry *fileEntry
+}
+
+// Options configures a [ZipFS].
+type Options struct {
+	// MaxDepth is the maximum number of path segments of the entries of the
+	// archive. Deeper entries are skipped. Zero means no limit.
+	MaxDepth int
+
+	// ExtendedTimestamps makes ModTime of files report the exact UTC time
+	// stored in the extended timestamp extra field (0x5455) of entries, if
+	// present. By default, ModTime is the time computed by [archive/zip],
+	// which may combine the MS-DOS date fields with a guessed time zone.
+	ExtendedTimestamps bool
+
+	// OnSkip, if not nil, is called for each entry of the archive skipped
+	// while building the index, with the reason.
+	OnSkip func(f *zip.File, err error)
+
+	// MaxFileSize is the maximum uncompressed size of a file that can be read.
+	// Reading larger files fails with [ErrSizeLimitExceeded]. Zero means no
+	// limit. This protects against zip bombs from untrusted sources.
+	MaxFileSize int64
+
+	// MaxTotalSize is the maximum number of bytes that can be read from all
+	// the files of the archive, over the life of the ZipFS. Once reached,
+	// reads fail with [ErrSizeLimitExceeded]. Zero means no limit.
+	MaxTotalSize int64
+
+	// LazyIndex delays building the index of the archive until the first
+	// access to the ZipFS, instead of building it in the constructor. The
+	// index is built exactly once, even if the first accesses are concurrent.
+	// OnSkip is then called at that time, from the goroutine of that access.
+	LazyIndex bool
+
+	// DecodeCP437 makes names of entries which are not encoded in UTF-8
+	// ([zip.FileHeader].NonUTF8: the UTF-8 flag, bit 11 of the general
+	// purpose flags, is unset and the name is not plain ASCII) be decoded
+	// from code page 437, the legacy encoding of zip archives, as created
+	// by old Windows tools. Entries are then accessed by their decoded
+	// names, which are also reported by the FileInfo and FileHeader.
+	// By default, the raw bytes of names are used: such entries can't be
+	// opened, as their names are not valid UTF-8 paths.
+	DecodeCP437 bool
+
+	// Password is used to decrypt encrypted entries. Entries encrypted with
+	// ZipCrypto (the traditional PKWARE encryption) are decrypted by ZipFS;
+	// other methods (such as AES) require a Decrypter. Without a password,
+	// opening an encrypted entry fails with [ErrEncrypted].
+	//
+	// ZipCrypto is known to be weak: the content of the archive should not
+	// be considered confidential. Also, a wrong password is detected only
+	// by the checksum verified at the end of the content in 1 case out of
+	// 256: data read before may be garbage.
+	Password string
+
+	// Decrypter, if not nil, decrypts entries encrypted with methods other
+	// than ZipCrypto, with Password.
+	Decrypter Decrypter
+
+	// PreserveMode makes the FileInfo of files and of directories which have
+	// an entry in the archive report the original mode stored in the
+	// archive (see [zip.FileHeader.Mode]), such as for an extraction tool.
+	// By default, as the ZipFS is read-only, the write permission bits are
+	// cleared (directories without an entry are always reported 0555).
+	PreserveMode bool
+}
+
+// ErrSizeLimitExceeded is returned when reading files beyond
+// [Options].MaxFileSize or [Options].MaxTotalSize.
+var ErrSizeLimitExceeded = errors.New("size limit exceeded")
+
+// ErrTooDeep is reported to [Options].OnSkip for entries deeper than [Options].MaxDepth.
+var ErrTooDeep = errors.New("path too deep")
+
+// ErrUnsafePath is reported to [Options].OnSkip for entries whose name is
+// absolute or escapes the root of the archive, such as "../evil" or
+// "a/../../b". Those entries are listed by [ZipFS.Rejected].
+var ErrUnsafePath = errors.New("unsafe path")
+
+// isUnsafePath reports whether the cleaned name of an entry is absolute or
+// escapes the root. Backslashes are handled as separators, as done by
+// Windows tools on extraction.
+func isUnsafePath(name string) bool {
+	name = path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	return path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../")
+}
+
+// Rejected returns the entries of the archive which are not exposed by the
+// ZipFS because their names are unsafe (see [ErrUnsafePath]). An extraction
+// tool may use it to warn about a malicious archive.
+//
+// The slice must not be modified.
+func (z *ZipFS) Rejected() []*zip.File {
+	z.index()
+	return z.rejected
 }
 
 // NewZipFS creates a new ZipFS instance from an [archive/zip.Reader].
 func NewZipFS(r *zip.Reader) *ZipFS {
+	return NewZipFSWithOptions(r, Options{})
+}
+
+// NewZipFSWithOptions creates a new ZipFS instance from an [archive/zip.Reader], with options.
+func NewZipFSWithOptions(r *zip.Reader, opts Options) *ZipFS {
 	z := &ZipFS{
 		reader: r,
-		files:  make(map[string]*zip.File, len(r.File)),
-		dirs: map[string]*dirInfo{
-			// Initialize root directory
-			".": &dirInfo{
-				name:    ".",
-				entries: []fs.DirEntry{},
-			},
-		},
+		opts:   opts,
+	}
+	if !opts.LazyIndex {
+		z.index()
 	}
-	z.buildIndex()
 	return z
 }
 
+// index builds the index, once.
+func (z *ZipFS) index() {
+	z.once.Do(z.buildIndex)
+}
+
+// NewFromReaderAt creates a ZipFS from the zip archive of the given size
+// read from r. r may be any [io.ReaderAt], such as an [*os.File] or a
+// memory-mapped file.
+//
+// r must remain valid as long as the ZipFS and the files opened from it are
+// used: the caller is responsible for releasing it (closing the file,
+// unmapping the memory...) when done.
+func NewFromReaderAt(r io.ReaderAt, size int64, opts Options) (*ZipFS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewZipFSWithOptions(zr, opts), nil
+}
+
+// NewFromBytes creates a ZipFS from the zip archive held in memory in b,
+// such as a downloaded or embedded archive. b must not be modified while the
+// ZipFS is used.
+func NewFromBytes(b []byte, opts Options) (*ZipFS, error) {
+	return NewFromReaderAt(bytes.NewReader(b), int64(len(b)), opts)
+}
+
+// OpenFile opens the zip archive at path name of the local filesystem, like
+// [zip.OpenReader].
+//
+// The ZipFS must be closed with [ZipFS.Close] to release the file.
+func OpenFile(name string, opts Options) (*ZipFS, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	z, err := NewFromReaderAt(f, fi.Size(), opts)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	z.closer = f
+	return z, nil
+}
+
+// Close releases the file opened by [OpenFile]. The files opened from the
+// ZipFS can't be read anymore.
+//
+// For a ZipFS created from a [zip.Reader] or an [io.ReaderAt], Close does
+// nothing: the caller remains responsible for releasing the source.
+func (z *ZipFS) Close() error {
+	if z.closer == nil {
+		return nil
+	}
+	return z.closer.Close()
+}
+
 // buildIndex creates the internal directory structure and file mappings.
 func (z *ZipFS) buildIndex() {
-	dirModTime := time.Now()
-	z.dirs["."].modTime = dirModTime
+	z.files = make(map[string]*zip.File, len(z.reader.File))
+	z.list = make([]fileItem, 0, len(z.reader.File))
+	z.dirs = map[string]*dirInfo{
+		// Initialize root directory
+		".": &dirInfo{
+			name:    ".",
+			entries: []fs.DirEntry{},
+		},
+	}
 
 	for _, f := range z.reader.File {
+		if z.opts.DecodeCP437 {
+			f = decodeName(f)
+		}
 		isDir := len(f.Name) == 0 || f.Name[len(f.Name)-1] == '/'
 		name := path.Clean(f.Name)
 		if name == "." {
 			continue
 		}
 
-		if name[0] == '/' { // Ignore absolute paths
+		if isUnsafePath(name) {
+			z.rejected = append(z.rejected, f)
+			z.skip(f, ErrUnsafePath)
+			continue
+		}
+
+		if z.opts.MaxDepth > 0 && strings.Count(name, "/") >= z.opts.MaxDepth {
+			z.skip(f, ErrTooDeep)
 			continue
 		}
 
@@ -60,17 +265,40 @@ func (z *ZipFS) buildIndex() {
 			if f.FileHeader.UncompressedSize64 != 0 {
 				continue
 			}
+			if dir, exists := z.dirs[name]; exists {
+				// Duplicate, or directory already synthesized for its content
+				dir.modTime = f.FileInfo().ModTime()
+				if z.opts.PreserveMode {
+					dir.mode = f.Mode() | fs.ModeDir
+				}
+				continue
+			}
 			dir := &dirInfo{
 				name:    path.Base(name),
 				modTime: f.FileInfo().ModTime(),
 			}
+			if z.opts.PreserveMode {
+				dir.mode = f.Mode() | fs.ModeDir
+			}
 			z.dirs[name] = dir
 			entry = dir
 		} else {
+			fe := &fileEntry{z: z, file: f}
+			entry = fe
+
+			if _, exists := z.files[name]; exists {
+				// Duplicate: the last one wins, as on extraction
+				z.files[name] = f
+				parent := z.dirs[path.Dir(name)]
+				i := slices.IndexFunc(parent.entries, func(e fs.DirEntry) bool { return e.Name() == entry.Name() })
+				parent.entries[i] = entry
+				i = slices.IndexFunc(z.list, func(item fileItem) bool { return item.name == name })
+				z.list[i].entry = fe
+				continue
+			}
 			// Add file to direct lookup
 			z.files[name] = f
-
-			entry = &fileEntry{file: f}
+			z.list = append(z.list, fileItem{name: name, entry: fe})
 		}
 
 		// Create entries for all parent directories up to root
@@ -84,7 +312,6 @@ func (z *ZipFS) buildIndex() {
 
 			parent = &dirInfo{
 				name:    path.Base(dir),
-				modTime: dirModTime,
 				entries: []fs.DirEntry{entry},
 			}
 			z.dirs[dir] = parent
@@ -93,10 +320,139 @@ func (z *ZipFS) buildIndex() {
 			dir = path.Dir(dir)
 		}
 	}
+
+	z.setDirModTimes()
+
+	// Sort once: ReadDir, dirReader.ReadDir and WalkDir share the sorted entries
+	for _, dir := range z.dirs {
+		slices.SortFunc(dir.entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+	}
+}
+
+// setDirModTimes sets the ModTime of synthesized directories to the newest
+// ModTime of their direct entries, so that it is derived from the content.
+// The root gets the newest ModTime of the whole archive.
+func (z *ZipFS) setDirModTimes() {
+	// Deepest directories first, so that subdirectories are done before
+	// their parent
+	names := slices.SortedFunc(maps.Keys(z.dirs), func(a, b string) int {
+		return strings.Count(b, "/") - strings.Count(a, "/")
+	})
+	var newest time.Time
+	for _, name := range names {
+		dir := z.dirs[name]
+		if name == "." {
+			continue
+		}
+		if dir.modTime.IsZero() {
+			dir.modTime = newestModTime(dir.entries)
+		}
+		newest = later(newest, dir.modTime)
+	}
+	for _, f := range z.files {
+		newest = later(newest, z.fileInfo(f).ModTime())
+	}
+	z.dirs["."].modTime = newest
+}
+
+// newestModTime returns the newest ModTime of entries.
+func newestModTime(entries []fs.DirEntry) time.Time {
+	var t time.Time
+	for _, e := range entries {
+		if fi, err := e.Info(); err == nil {
+			t = later(t, fi.ModTime())
+		}
+	}
+	return t
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// openFile opens the content of a file, applying the size limits.
+func (z *ZipFS) openFile(f *zip.File) (io.ReadCloser, error) {
+	if err := z.checkFileSize(f); err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser
+	var err error
+	if isEncrypted(f) {
+		rc, err = z.openEncrypted(f)
+	} else {
+		rc, err = f.Open()
+	}
+	if err != nil || z.opts.MaxTotalSize <= 0 {
+		return rc, err
+	}
+	return &limitedReader{z: z, ReadCloser: rc}, nil
+}
+
+func (z *ZipFS) checkFileSize(f *zip.File) error {
+	if z.opts.MaxFileSize > 0 && f.UncompressedSize64 > uint64(z.opts.MaxFileSize) {
+		return ErrSizeLimitExceeded
+	}
+	return nil
+}
+
+// reserve accounts for n bytes to be read, against MaxTotalSize.
+// It returns the number of bytes that can be read.
+func (z *ZipFS) reserve(n int) (int, error) {
+	limit := z.opts.MaxTotalSize
+	if limit <= 0 || n == 0 {
+		return n, nil
+	}
+	for {
+		read := z.read.Load()
+		if read >= limit {
+			return 0, ErrSizeLimitExceeded
+		}
+		m := min(int64(n), limit-read)
+		if z.read.CompareAndSwap(read, read+m) {
+			return int(m), nil
+		}
+	}
+}
+
+// release gives back bytes reserved but not read.
+func (z *ZipFS) release(n int) {
+	if n > 0 && z.opts.MaxTotalSize > 0 {
+		z.read.Add(-int64(n))
+	}
+}
+
+// limitedReader applies MaxTotalSize to the reads of a file.
+type limitedReader struct {
+	z *ZipFS
+	io.ReadCloser
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	m, err := l.z.reserve(len(b))
+	if err != nil {
+		return 0, err
+	}
+	n, err := l.ReadCloser.Read(b[:m])
+	l.z.release(m - n)
+	return n, err
+}
+
+// skip reports an entry ignored by buildIndex.
+func (z *ZipFS) skip(f *zip.File, err error) {
+	if z.opts.OnSkip != nil {
+		z.opts.OnSkip(f, err)
+	}
 }
 
 // Open implements fs.FS
 func (z *ZipFS) Open(name string) (fs.File, error) {
+	z.index()
+
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
 	}
@@ -110,7 +466,7 @@ func (z *ZipFS) Open(name string) (fs.File, error) {
 
 	// Check if it's a file
 	if file, ok := z.files[name]; ok {
-		return &fileReader{file: file}, nil
+		return &fileReader{z: z, file: file}, nil
 	}
 
 	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
@@ -118,6 +474,8 @@ func (z *ZipFS) Open(name string) (fs.File, error) {
 
 // ReadDir implements [fs.ReadDirFS].
 func (z *ZipFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	z.index()
+
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
 	}
@@ -127,15 +485,255 @@ func (z *ZipFS) ReadDir(name string) ([]fs.DirEntry, error) {
 	if !ok {
 		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
 	}
-	entries := append([]fs.DirEntry(nil), dir.entries...)
-	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
-		return strings.Compare(a.Name(), b.Name())
-	})
-	return entries, nil
+	return slices.Clone(dir.entries), nil
+}
+
+// WalkDir is like [fs.WalkDir], but walks the index of the archive
+// directly: entries of each directory are sorted when the index is built,
+// and no directory is opened.
+func (z *ZipFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	z.index()
+
+	var err error
+	if !fs.ValidPath(root) {
+		err = fn(root, nil, &fs.PathError{Op: "walkdir", Path: root, Err: fs.ErrInvalid})
+	} else if dir, ok := z.dirs[path.Clean(root)]; ok {
+		err = z.walkDir(root, dir, fn)
+	} else if file, ok := z.files[path.Clean(root)]; ok {
+		err = fn(root, &fileEntry{z: z, file: file}, nil)
+	} else {
+		err = fn(root, nil, &fs.PathError{Op: "walkdir", Path: root, Err: fs.ErrNotExist})
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (z *ZipFS) walkDir(name string, dir *dirInfo, fn fs.WalkDirFunc) error {
+	if err := fn(name, dir, nil); err != nil {
+		if err == fs.SkipDir {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range dir.entries {
+		entryName := path.Join(name, entry.Name())
+		var err error
+		if entry.IsDir() {
+			err = z.walkDir(entryName, z.dirs[entryName], fn)
+		} else {
+			err = fn(entryName, entry, nil)
+		}
+		if err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Files returns an iterator over the files of the archive (not the
+// directories), in the order of the archive, with their path and entry.
+// Unlike [fs.WalkDir], no directory is read and the iteration doesn't
+// allocate for each file.
+//
+// Entries skipped while building the index (see [Options].OnSkip) are not
+// listed. For duplicate names, the last entry is listed at the position of
+// the first one.
+func (z *ZipFS) Files() iter.Seq2[string, fs.DirEntry] {
+	z.index()
+	return func(yield func(string, fs.DirEntry) bool) {
+		for _, item := range z.list {
+			if !yield(item.name, item.entry) {
+				return
+			}
+		}
+	}
+}
+
+// OpenDirAsZip returns a new zip archive, streamed while it is read, with
+// the subtree of the directory name: paths in the new archive are relative
+// to name. This allows to extract a subdirectory of a large archive cheaply:
+// the data of the entries is copied without being decompressed (the
+// compression method, modification time and other attributes of the
+// entries are preserved; encrypted entries stay encrypted).
+//
+// The archive has an entry for each subdirectory, then the files in the
+// order of [ZipFS.Files].
+//
+// The size limits of [Options] don't apply.
+func (z *ZipFS) OpenDirAsZip(name string) (io.ReadCloser, error) {
+	z.index()
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	name = path.Clean(name)
+	if _, ok := z.dirs[name]; !ok {
+		if _, ok := z.files[name]; ok {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(z.writeDirZip(pw, prefix))
+	}()
+	return pr, nil
+}
+
+// writeDirZip writes to w a zip archive of the entries under prefix.
+func (z *ZipFS) writeDirZip(w io.Writer, prefix string) error {
+	zw := zip.NewWriter(w)
+
+	for _, dirName := range slices.Sorted(maps.Keys(z.dirs)) {
+		rel, ok := strings.CutPrefix(dirName, prefix)
+		if !ok || rel == "" || dirName == "." {
+			continue
+		}
+		hdr := &zip.FileHeader{
+			Name:     rel + "/",
+			Modified: z.dirs[dirName].modTime,
+		}
+		hdr.SetMode(z.dirs[dirName].Mode())
+		if _, err := zw.CreateHeader(hdr); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range z.list {
+		rel, ok := strings.CutPrefix(item.name, prefix)
+		if !ok {
+			continue
+		}
+		hdr := item.entry.file.FileHeader
+		hdr.Name = rel
+		fw, err := zw.CreateRaw(&hdr)
+		if err != nil {
+			return err
+		}
+		raw, err := item.entry.file.OpenRaw()
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(fw, raw); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// NumFiles returns the number of files of the archive (including symbolic
+// links), as exposed by the ZipFS: entries skipped (see [Options].OnSkip)
+// are not counted.
+func (z *ZipFS) NumFiles() int {
+	z.index()
+	return len(z.files)
+}
+
+// NumDirs returns the number of directories of the archive, including the
+// directories without an entry in the archive, but excluding the root.
+func (z *ZipFS) NumDirs() int {
+	z.index()
+	return len(z.dirs) - 1
+}
+
+// TotalUncompressedSize returns the sum of the uncompressed sizes of the
+// files counted by [ZipFS.NumFiles].
+func (z *ZipFS) TotalUncompressedSize() int64 {
+	z.index()
+	var size uint64
+	for _, f := range z.files {
+		size += f.UncompressedSize64
+	}
+	return int64(size)
+}
+
+// TotalCompressedSize returns the sum of the compressed sizes of the files
+// counted by [ZipFS.NumFiles], as stored in the archive.
+func (z *ZipFS) TotalCompressedSize() int64 {
+	z.index()
+	var size uint64
+	for _, f := range z.files {
+		size += f.CompressedSize64
+	}
+	return int64(size)
+}
+
+// Stat implements [fs.StatFS]. The information comes from the index of the
+// archive: no file is opened.
+func (z *ZipFS) Stat(name string) (fs.FileInfo, error) {
+	z.index()
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	cleanName := path.Clean(name)
+	if dir, ok := z.dirs[cleanName]; ok {
+		return dir, nil
+	}
+	if file, ok := z.files[cleanName]; ok {
+		return z.fileInfo(file), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadLink returns the target of the symbolic link name, stored as the
+// content of the entry. Symbolic links are entries with [fs.ModeSymlink] in
+// the Unix mode of their header.
+//
+// With [ZipFS.Lstat], it implements the fs.ReadLinkFS interface of Go 1.25.
+func (z *ZipFS) ReadLink(name string) (string, error) {
+	z.index()
+
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	file, ok := z.files[path.Clean(name)]
+	if !ok {
+		if _, ok := z.dirs[path.Clean(name)]; ok {
+			return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+		}
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	if file.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	rc, err := z.openFile(file)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(rc)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return string(target), nil
+}
+
+// Lstat returns the [fs.FileInfo] of name, without following symbolic links.
+// As [ZipFS.Stat] doesn't follow symbolic links either, it is the same as Stat.
+func (z *ZipFS) Lstat(name string) (fs.FileInfo, error) {
+	fi, err := z.Stat(name)
+	if pe, ok := err.(*fs.PathError); ok {
+		pe.Op = "lstat"
+	}
+	return fi, err
 }
 
 // ReadFile implements fs.ReadFileFS
 func (z *ZipFS) ReadFile(name string) ([]byte, error) {
+	z.index()
+
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
 	}
@@ -146,17 +744,54 @@ func (z *ZipFS) ReadFile(name string) ([]byte, error) {
 		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
 	}
 
-	rc, err := file.Open()
+	rc, err := z.openFile(file)
 	if err != nil {
 		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
 	}
 	defer rc.Close()
 
-	return io.ReadAll(rc)
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	return b, nil
+}
+
+// Glob implements [fs.GlobFS]. The pattern is matched against the index of
+// the archive, without walking directories.
+func (z *ZipFS) Glob(pattern string) ([]string, error) {
+	z.index()
+
+	// Check pattern syntax
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if pattern == "." {
+		return []string{"."}, nil
+	}
+
+	var matches []string
+	for name := range z.files {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	for name := range z.dirs {
+		if name == "." {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	slices.Sort(matches)
+	return matches, nil
 }
 
 // Sub implements fs.SubFS
 func (z *ZipFS) Sub(dir string) (fs.FS, error) {
+	z.index()
+
 	if !fs.ValidPath(dir) {
 		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
 	}
@@ -227,6 +862,33 @@ func (s *subFS) ReadDir(name string) ([]fs.DirEntry, error) {
 	return entries, err
 }
 
+func (s *subFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	fi, err := s.parent.Stat(path.Join(s.prefix, name))
+	s.rebaseError(err)
+	return fi, err
+}
+
+func (s *subFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := s.parent.ReadLink(path.Join(s.prefix, name))
+	s.rebaseError(err)
+	return target, err
+}
+
+func (s *subFS) Lstat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrInvalid}
+	}
+	fi, err := s.parent.Lstat(path.Join(s.prefix, name))
+	s.rebaseError(err)
+	return fi, err
+}
+
 func (s *subFS) ReadFile(name string) ([]byte, error) {
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
@@ -236,6 +898,39 @@ func (s *subFS) ReadFile(name string) ([]byte, error) {
 	return b, err
 }
 
+func (s *subFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	// Escape the meta characters of the prefix
+	prefix := globEscaper.Replace(s.prefix)
+	matches, err := s.parent.Glob(path.Join(prefix, pattern))
+	for i, name := range matches {
+		if name == s.prefix {
+			matches[i] = "."
+		} else {
+			matches[i] = strings.TrimPrefix(name, s.prefix+"/")
+		}
+	}
+	return matches, err
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+// Files is like [ZipFS.Files], for the files under the directory of s.
+func (s *subFS) Files() iter.Seq2[string, fs.DirEntry] {
+	prefix := s.prefix + "/"
+	return func(yield func(string, fs.DirEntry) bool) {
+		for name, entry := range s.parent.Files() {
+			if rel, ok := strings.CutPrefix(name, prefix); ok {
+				if !yield(rel, entry) {
+					return
+				}
+			}
+		}
+	}
+}
+
 func (s *subFS) Sub(dir string) (fs.FS, error) {
 	if !fs.ValidPath(dir) {
 		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
@@ -250,76 +945,302 @@ type (
 
 	roFileInfo struct {
 		fsFileInfo
+		modTime      time.Time // overrides fsFileInfo.ModTime if not zero
+		preserveMode bool      // see Options.PreserveMode
 	}
 )
 
+// fileInfo returns the [fs.FileInfo] of a file entry.
+func (z *ZipFS) fileInfo(f *zip.File) fs.FileInfo {
+	fi := roFileInfo{fsFileInfo: f.FileInfo(), preserveMode: z.opts.PreserveMode}
+	if z.opts.ExtendedTimestamps {
+		fi.modTime = extendedModTime(f.Extra)
+	}
+	return fi
+}
+
+// extTimeExtraID is the ID of the extended timestamp extra field.
+const extTimeExtraID = 0x5455
+
+// extendedModTime returns the modification time from the extended
+// timestamp extra field, or the zero time.
+func extendedModTime(extra []byte) time.Time {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		field := extra[:size]
+		extra = extra[size:]
+		// flags (1 byte) then mtime (int32) if bit 0 of flags is set
+		if id != extTimeExtraID || len(field) < 5 || field[0]&1 == 0 {
+			continue
+		}
+		return time.Unix(int64(int32(binary.LittleEndian.Uint32(field[1:]))), 0).UTC()
+	}
+	return time.Time{}
+}
+
+func (rfi roFileInfo) ModTime() time.Time {
+	if !rfi.modTime.IsZero() {
+		return rfi.modTime
+	}
+	return rfi.fsFileInfo.ModTime()
+}
+
 func (rfi roFileInfo) Mode() fs.FileMode {
+	if rfi.preserveMode {
+		return rfi.fsFileInfo.Mode()
+	}
 	// Remove W permissions
 	return rfi.fsFileInfo.Mode() &^ 0222
 }
 
+// Sys returns the *[zip.FileHeader] of the entry, with the original mode
+// (see [zip.FileHeader.Mode]), compression method, CRC32...
 func (rfi roFileInfo) Sys() any {
-	return nil
+	return rfi.fsFileInfo.Sys()
 }
 
 func (rfi roFileInfo) String() string {
 	return fs.FormatFileInfo(rfi)
 }
 
-// fileReader implements [fs.File] for zip archive entries.
+// fileReader implements [fs.File], [io.Seeker], [io.ReaderAt] and
+// [io.WriterTo] for zip archive entries.
 type fileReader struct {
+	z    *ZipFS
 	file *zip.File
-	rc   io.ReadCloser
+	rc   io.ReadCloser // decompressed content, from pos
+	pos  int64
+
+	// raw is set once random access (Seek, ReadAt) has been used on a stored
+	// (uncompressed) entry: reads then go directly to the data in the archive.
+	raw *io.SectionReader
+
+	// content is the decompressed content of a compressed entry, loaded on
+	// the first ReadAt.
+	content []byte
 }
 
 func (f *fileReader) Stat() (fs.FileInfo, error) {
-	return roFileInfo{f.file.FileInfo()}, nil
+	return f.z.fileInfo(f.file), nil
 }
 
 func (f *fileReader) Read(b []byte) (int, error) {
+	if f.raw != nil {
+		n, err := f.readRawAt(b, f.pos)
+		f.pos += int64(n)
+		return n, err
+	}
 	if f.rc == nil {
 		var err error
-		f.rc, err = f.file.Open()
+		f.rc, err = f.z.openFile(f.file)
 		if err != nil {
 			return 0, err
 		}
 	}
-	return f.rc.Read(b)
+	n, err := f.rc.Read(b)
+	f.pos += int64(n)
+	return n, err
 }
 
-func (f *fileReader) Close() error {
-	if f.rc != nil {
-		err := f.rc.Close()
-		f.rc = nil
+// WriteTo implements [io.WriterTo], so [io.Copy] streams the rest of the
+// content directly from the decompressor (or from the archive, once random
+// access has been used on a stored entry) to w, without intermediate buffer
+// if w implements [io.ReaderFrom].
+func (f *fileReader) WriteTo(w io.Writer) (int64, error) {
+	var r io.Reader
+	if f.raw != nil {
+		r = io.NewSectionReader(f.raw, f.pos, f.raw.Size()-f.pos)
+		if f.z.opts.MaxTotalSize > 0 {
+			r = &limitedReader{z: f.z, ReadCloser: io.NopCloser(r)}
+		}
+	} else {
+		if f.rc == nil {
+			var err error
+			if f.rc, err = f.z.openFile(f.file); err != nil {
+				return 0, err
+			}
+		}
+		r = f.rc
+	}
+	n, err := io.Copy(w, r)
+	f.pos += n
+	return n, err
+}
+
+// Seek implements [io.Seeker]. Seeking before the start or past the end of
+// the file is an error.
+//
+// For stored (uncompressed) entries, the data is accessed directly in the
+// archive. For compressed entries, seeking backwards reopens the entry and
+// decompresses again from the start.
+func (f *fileReader) Seek(offset int64, whence int) (int64, error) {
+	size := int64(f.file.UncompressedSize64)
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.file.Name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 || offset > size {
+		return 0, &fs.PathError{Op: "seek", Path: f.file.Name, Err: fs.ErrInvalid}
+	}
+
+	if err := f.openRaw(); err != nil {
+		return 0, &fs.PathError{Op: "seek", Path: f.file.Name, Err: err}
+	}
+	if f.raw != nil {
+		f.pos = offset
+		return offset, nil
+	}
+
+	if offset < f.pos {
+		f.closeReader()
+		f.pos = 0
+	}
+	if offset > f.pos {
+		if f.rc == nil {
+			var err error
+			if f.rc, err = f.z.openFile(f.file); err != nil {
+				return 0, &fs.PathError{Op: "seek", Path: f.file.Name, Err: err}
+			}
+		}
+		n, err := io.CopyN(io.Discard, f.rc, offset-f.pos)
+		f.pos += n
+		if err != nil {
+			return f.pos, &fs.PathError{Op: "seek", Path: f.file.Name, Err: err}
+		}
+	}
+	return offset, nil
+}
+
+// openRaw switches a stored entry to direct access to the archive, unless
+// it is encrypted.
+func (f *fileReader) openRaw() error {
+	if f.raw != nil || f.file.Method != zip.Store || isEncrypted(f.file) {
+		return nil
+	}
+	if err := f.z.checkFileSize(f.file); err != nil {
 		return err
 	}
+	r, err := f.file.OpenRaw()
+	if err != nil {
+		return err
+	}
+	if sr, ok := r.(*io.SectionReader); ok {
+		f.closeReader()
+		f.raw = sr
+	}
 	return nil
 }
 
+// ReadAt implements [io.ReaderAt]. It doesn't affect the offset of Read and
+// Seek.
+//
+// For stored (uncompressed) entries, the data is read directly from the
+// archive. For compressed entries, the whole content is decompressed and
+// kept in memory on the first call.
+func (f *fileReader) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.file.Name, Err: fs.ErrInvalid}
+	}
+	if err := f.openRaw(); err != nil {
+		return 0, &fs.PathError{Op: "readat", Path: f.file.Name, Err: err}
+	}
+	if f.raw != nil {
+		return f.readRawAt(b, off)
+	}
+
+	if f.content == nil {
+		rc, err := f.z.openFile(f.file)
+		if err != nil {
+			return 0, &fs.PathError{Op: "readat", Path: f.file.Name, Err: err}
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return 0, &fs.PathError{Op: "readat", Path: f.file.Name, Err: err}
+		}
+		f.content = content
+	}
+	if off >= int64(len(f.content)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.content[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readRawAt reads the data of a stored entry, applying MaxTotalSize.
+func (f *fileReader) readRawAt(b []byte, off int64) (int, error) {
+	m, err := f.z.reserve(len(b))
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.raw.ReadAt(b[:m], off)
+	f.z.release(m - n)
+	if err == nil && m < len(b) {
+		err = ErrSizeLimitExceeded
+	}
+	return n, err
+}
+
+func (f *fileReader) closeReader() error {
+	if f.rc == nil {
+		return nil
+	}
+	err := f.rc.Close()
+	f.rc = nil
+	return err
+}
+
+func (f *fileReader) Close() error {
+	f.raw = nil
+	f.content = nil
+	return f.closeReader()
+}
+
 // fileEntry implements [fs.DirEntry] for real zip entries.
 type fileEntry struct {
+	z    *ZipFS
 	file *zip.File
 }
 
 func (i fileEntry) Name() string               { return path.Base(i.file.Name) }
 func (i fileEntry) IsDir() bool                { return false }
 func (i fileEntry) Type() fs.FileMode          { return i.file.FileInfo().Mode().Type() }
-func (i fileEntry) Info() (fs.FileInfo, error) { return roFileInfo{i.file.FileInfo()}, nil }
+func (i fileEntry) Info() (fs.FileInfo, error) { return i.z.fileInfo(i.file), nil }
 
 // dirInfo implements [fs.DirEntry] and [fs.FileInfo] for directories.
 type dirInfo struct {
 	name    string
-	modTime time.Time
-	entries []fs.DirEntry
+	modTime time.Time     // zero until computed for synthesized directories
+	mode    fs.FileMode   // mode of the entry with Options.PreserveMode, else 0
+	entries []fs.DirEntry // sorted by name once the index is built; shared
 }
 
 func (i *dirInfo) Name() string       { return i.name }
 func (i *dirInfo) Size() int64        { return 0 }
-func (i *dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
 func (i *dirInfo) ModTime() time.Time { return i.modTime }
 func (i *dirInfo) IsDir() bool        { return true }
 func (i *dirInfo) Sys() any           { return nil }
 
+func (i *dirInfo) Mode() fs.FileMode {
+	if i.mode != 0 {
+		return i.mode
+	}
+	return fs.ModeDir | 0555
+}
+
 func (i *dirInfo) Type() fs.FileMode          { return fs.ModeDir }
 func (i *dirInfo) Info() (fs.FileInfo, error) { return i, nil }
 
@@ -351,13 +1272,18 @@ func (d *dirReader) Close() error {
 	return nil
 }
 
-// ReadDir reads the contents of the directory and returns a slice of entries.
+// ReadDir reads the contents of the directory and returns a slice of entries,
+// sorted by name.
 // If n > 0, ReadDir returns at most n entries. In this case, if ReadDir returns an empty slice,
 // it will return an error explaining why. At the end of a directory, the error is io.EOF.
+//
+// With n > 0, the slice returned shares the storage of the index (with its
+// capacity clipped, so appending to it doesn't modify the index): it must
+// not be modified. With n <= 0, it is a copy.
 func (d *dirReader) ReadDir(n int) ([]fs.DirEntry, error) {
 	if n <= 0 {
 		// Return all remaining entries
-		remaining := d.info.entries[d.pos:]
+		remaining := slices.Clone(d.info.entries[d.pos:])
 		d.pos = len(d.info.entries)
 		return remaining, nil
 	}
@@ -372,7 +1298,7 @@ func (d *dirReader) ReadDir(n int) ([]fs.DirEntry, error) {
 		end = len(d.info.entries)
 	}
 
-	entries := d.info.entries[d.pos:end]
+	entries := d.info.entries[d.pos:end:end]
 	d.pos = end
 
 	if len(entries) == 0 {
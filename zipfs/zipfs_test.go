@@ -3,10 +3,26 @@ package zipfs
 import (
 	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
 	"io/fs"
+	"iter"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
+	"testing/iotest"
+	"time"
 )
 
 var (
@@ -15,6 +31,8 @@ var (
 		fs.ReadDirFS
 		fs.ReadFileFS
 		fs.SubFS
+		fs.GlobFS
+		fs.StatFS
 	}{
 		(*ZipFS)(nil),
 		(*subFS)(nil),
@@ -252,3 +270,1713 @@ func TestSubFSTestSuite(t *testing.T) {
 		t.Errorf("fstest.TestFS failed on sub-filesystem: %v", err)
 	}
 }
+
+func TestMaxDepth(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	deep := strings.Repeat("d/", 1000) + "deep.txt"
+	for _, name := range []string{"a.txt", "b/c/d.txt", deep} {
+		if _, err := w.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var skipped []string
+	zipFS := NewZipFSWithOptions(zr, Options{
+		MaxDepth: 3,
+		OnSkip: func(f *zip.File, err error) {
+			if !errors.Is(err, ErrTooDeep) {
+				t.Errorf("%s: unexpected reason: %v", f.Name, err)
+			}
+			skipped = append(skipped, f.Name)
+		},
+	})
+	if len(skipped) != 1 || skipped[0] != deep {
+		t.Errorf("skipped: got %q", skipped)
+	}
+	if err := fstest.TestFS(zipFS, "a.txt", "b/c/d.txt"); err != nil {
+		t.Error(err)
+	}
+	if _, err := zipFS.Open("d"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("deep entry indexed: %v", err)
+	}
+}
+
+func TestExtendedTimestamps(t *testing.T) {
+	extTime := time.Date(2024, 6, 1, 12, 34, 56, 0, time.UTC)
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	extra := []byte{0x55, 0x54, 5, 0, 1, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(extra[5:], uint32(extTime.Unix()))
+	hdr := &zip.FileHeader{Name: "ext.txt", Extra: extra}
+	hdr.SetModTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) // MS-DOS fields only
+	if _, err := w.CreateHeader(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Create("plain.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{ExtendedTimestamps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fs.Stat(zipFS, "ext.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mt := fi.ModTime(); !mt.Equal(extTime) || mt.Location() != time.UTC {
+		t.Errorf("ModTime: got %v, want %v", mt, extTime)
+	}
+
+	// Entries without the extra field keep the default behavior
+	fi, err = fs.Stat(zipFS, "plain.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fiDefault, _ := fs.Stat(defaultFS, "plain.txt")
+	if !fi.ModTime().Equal(fiDefault.ModTime()) {
+		t.Errorf("ModTime: got %v, want %v", fi.ModTime(), fiDefault.ModTime())
+	}
+
+	if err := fstest.TestFS(zipFS, "ext.txt", "plain.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileSeek(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, method := range map[string]uint16{"stored.txt": zip.Store, "deflated.txt": zip.Deflate} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"stored.txt", "deflated.txt"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := zipFS.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			seeker, ok := f.(io.ReadSeeker)
+			if !ok {
+				t.Fatal("not an io.Seeker")
+			}
+
+			b := make([]byte, 4)
+			for _, step := range []struct {
+				offset int64
+				whence int
+				pos    int64
+			}{
+				{5000, io.SeekStart, 5000},
+				{-1000, io.SeekCurrent, 4004}, // after reading 4 bytes
+				{-4, io.SeekEnd, int64(len(content)) - 4},
+				{3, io.SeekStart, 3},
+				{0, io.SeekCurrent, 7},
+			} {
+				pos, err := seeker.Seek(step.offset, step.whence)
+				if err != nil {
+					t.Fatalf("Seek(%d, %d): %v", step.offset, step.whence, err)
+				}
+				if pos != step.pos {
+					t.Errorf("Seek(%d, %d): got %d, want %d", step.offset, step.whence, pos, step.pos)
+				}
+				if _, err := io.ReadFull(seeker, b); err != nil {
+					t.Fatalf("Read at %d: %v", pos, err)
+				}
+				if want := content[pos : pos+4]; !bytes.Equal(b, want) {
+					t.Errorf("Read at %d: got %q, want %q", pos, b, want)
+				}
+			}
+
+			// Out of bounds
+			for _, offset := range []int64{-1, int64(len(content)) + 1} {
+				if _, err := seeker.Seek(offset, io.SeekStart); !errors.Is(err, fs.ErrInvalid) {
+					t.Errorf("Seek(%d): got %v, want fs.ErrInvalid", offset, err)
+				}
+			}
+
+			// At EOF
+			if _, err := seeker.Seek(0, io.SeekEnd); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := seeker.Read(b); n != 0 || err != io.EOF {
+				t.Errorf("Read at EOF: got %d, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestFileReadAt(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, method := range map[string]uint16{"stored.txt": zip.Store, "deflated.txt": zip.Deflate} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"stored.txt", "deflated.txt"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := zipFS.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			ra, ok := f.(io.ReaderAt)
+			if !ok {
+				t.Fatal("not an io.ReaderAt")
+			}
+
+			// Sequential read in progress
+			b := make([]byte, 3)
+			if _, err := io.ReadFull(f, b); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := iotest.TestReader(io.NewSectionReader(ra, 0, int64(len(content))), content); err != nil {
+				t.Error(err)
+			}
+
+			// Read offset is not affected
+			if _, err := io.ReadFull(f, b); err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "345" {
+				t.Errorf("Read after ReadAt: got %q, want %q", b, "345")
+			}
+
+			if n, err := ra.ReadAt(b, int64(len(content))-1); n != 1 || err != io.EOF {
+				t.Errorf("ReadAt at end: got %d, %v", n, err)
+			}
+			if _, err := ra.ReadAt(b, -1); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("ReadAt(-1): got %v, want fs.ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipFS := NewZipFS(zr)
+	sub, err := zipFS.Sub("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		fsys    fs.FS
+		pattern string
+		want    []string
+	}{
+		{zipFS, "dir/subdir/*.txt", []string{"dir/subdir/a.txt", "dir/subdir/b.txt"}},
+		{zipFS, "*/file*.txt", []string{"dir/file.txt", "other/file2.txt"}},
+		{zipFS, "*", []string{"dir", "empty", "hello.txt", "other"}},
+		{zipFS, "dir/sub*", []string{"dir/subdir"}},
+		{zipFS, "nothing*", nil},
+		{zipFS, ".", []string{"."}},
+		{sub, "subdir/*.txt", []string{"subdir/a.txt", "subdir/b.txt"}},
+		{sub, "*", []string{"file.txt", "subdir"}},
+		{sub, "[a-f]*", []string{"file.txt"}},
+		{sub, ".", []string{"."}},
+	} {
+		got, err := fs.Glob(tt.fsys, tt.pattern)
+		if err != nil {
+			t.Errorf("%q: %v", tt.pattern, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+
+	for _, fsys := range []fs.FS{zipFS, sub} {
+		if _, err := fs.Glob(fsys, "[a-"); !errors.Is(err, path.ErrBadPattern) {
+			t.Errorf("bad pattern: got %v", err)
+		}
+	}
+}
+
+func TestStat(t *testing.T) {
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipFS := NewZipFS(zr)
+	sub, err := zipFS.Sub("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		fsys  fs.FS
+		name  string
+		base  string
+		isDir bool
+		size  int64
+	}{
+		{zipFS, "hello.txt", "hello.txt", false, 13},
+		{zipFS, "dir/subdir", "subdir", true, 0},
+		{zipFS, "empty", "empty", true, 0},
+		{zipFS, ".", ".", true, 0},
+		{sub, "file.txt", "file.txt", false, 17},
+		{sub, "subdir/a.txt", "a.txt", false, 13},
+	} {
+		fi, err := fs.Stat(tt.fsys, tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if fi.Name() != tt.base || fi.IsDir() != tt.isDir || fi.Size() != tt.size {
+			t.Errorf("%s: got %s", tt.name, fs.FormatFileInfo(fi))
+		}
+		if fi.Mode()&0o222 != 0 {
+			t.Errorf("%s: writable mode %v", tt.name, fi.Mode())
+		}
+	}
+
+	_, err = fs.Stat(sub, "missing.txt")
+	var pe *fs.PathError
+	if !errors.As(err, &pe) || !errors.Is(err, fs.ErrNotExist) || pe.Path != "missing.txt" {
+		t.Errorf("missing.txt: got %v", err)
+	}
+}
+
+func TestLazyIndexConcurrent(t *testing.T) {
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var builds atomic.Int32
+	zipFS := NewZipFSWithOptions(zr, Options{
+		LazyIndex: true,
+		MaxDepth:  2, // "dir/subdir/*" entries are skipped
+		OnSkip: func(*zip.File, error) {
+			builds.Add(1)
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := []string{"hello.txt", "dir/file.txt", "other/file2.txt"}[i%3]
+			f, err := zipFS.Open(name)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer f.Close()
+			if _, err := io.ReadAll(f); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// OnSkip is called once per skipped entry: the index is built once
+	if n := builds.Load(); n != 2 {
+		t.Errorf("OnSkip called %d times, want 2", n)
+	}
+	if err := fstest.TestFS(zipFS, "hello.txt", "dir/file.txt", "other/file2.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestConcurrentAccess checks, with the race detector (go test -race), that
+// the methods of a ZipFS can be called from multiple goroutines.
+func TestConcurrentAccess(t *testing.T) {
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipFS := NewZipFSWithOptions(zr, Options{MaxTotalSize: 1 << 20})
+	files := map[string]string{
+		"hello.txt":        "Hello, World!",
+		"dir/file.txt":     "File in directory",
+		"dir/subdir/a.txt": "Nested file A",
+		"other/file2.txt":  "Another file",
+	}
+	names := slices.Sorted(maps.Keys(files))
+
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := names[i%len(names)]
+
+			f, err := zipFS.Open(name)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer f.Close()
+			b := make([]byte, 5)
+			if _, err := f.(io.ReaderAt).ReadAt(b, 1); err != nil || string(b) != files[name][1:6] {
+				t.Errorf("%s: ReadAt: got %q, %v", name, b, err)
+			}
+			if b, err := io.ReadAll(f); err != nil || string(b) != files[name] {
+				t.Errorf("%s: Read: got %q, %v", name, b, err)
+			}
+			if b, err := zipFS.ReadFile(name); err != nil || string(b) != files[name] {
+				t.Errorf("%s: ReadFile: got %q, %v", name, b, err)
+			}
+			if _, err := zipFS.Stat(name); err != nil {
+				t.Error(err)
+			}
+			if _, err := zipFS.ReadDir(path.Dir(name)); err != nil {
+				t.Error(err)
+			}
+			if _, err := zipFS.Glob("*/*.txt"); err != nil {
+				t.Error(err)
+			}
+			if err := zipFS.WalkDir(".", func(string, fs.DirEntry, error) error { return nil }); err != nil {
+				t.Error(err)
+			}
+			sub, err := zipFS.Sub("dir")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := fs.ReadDir(sub, "."); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFileInfoSys(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	hdr := &zip.FileHeader{Name: "run.sh", Method: zip.Store, Comment: "script"}
+	hdr.SetMode(0o755)
+	f, err := w.CreateHeader(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("#!/bin/sh\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := fs.Stat(NewZipFS(zr), "run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o555 {
+		t.Errorf("Mode: got %v, want write bits masked", fi.Mode())
+	}
+	fh, ok := fi.Sys().(*zip.FileHeader)
+	if !ok {
+		t.Fatalf("Sys: got %T, want *zip.FileHeader", fi.Sys())
+	}
+	if fh.Method != zip.Store || fh.Comment != "script" || fh.Mode().Perm() != 0o755 || fh.CRC32 == 0 {
+		t.Errorf("Sys: got %+v", fh)
+	}
+}
+
+func TestReadLink(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	hdr := &zip.FileHeader{Name: "dir/link"}
+	hdr.SetMode(fs.ModeSymlink | 0o777)
+	f, err := w.CreateHeader(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("../target.txt"))
+	f, err = w.Create("target.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("target"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := zipFS.Sub("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		fsys interface {
+			ReadLink(string) (string, error)
+			Lstat(string) (fs.FileInfo, error)
+		}
+		name string
+	}{
+		{zipFS, "dir/link"},
+		{sub.(*subFS), "link"},
+	} {
+		target, err := tt.fsys.ReadLink(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != "../target.txt" {
+			t.Errorf("ReadLink(%q): got %q", tt.name, target)
+		}
+		fi, err := tt.fsys.Lstat(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Type() != fs.ModeSymlink {
+			t.Errorf("Lstat(%q): got mode %v", tt.name, fi.Mode())
+		}
+	}
+
+	for name, want := range map[string]error{
+		"target.txt": fs.ErrInvalid,
+		"dir":        fs.ErrInvalid,
+		"missing":    fs.ErrNotExist,
+	} {
+		if _, err := zipFS.ReadLink(name); !errors.Is(err, want) {
+			t.Errorf("ReadLink(%q): got %v, want %v", name, err, want)
+		}
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, method := range map[string]uint16{
+		"small.txt":  zip.Deflate,
+		"big.txt":    zip.Deflate, // compresses very well
+		"stored.txt": zip.Store,
+	} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := 100
+		if name == "big.txt" {
+			size = 1 << 20
+		}
+		f.Write(bytes.Repeat([]byte{'a'}, size))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("MaxFileSize", func(t *testing.T) {
+		zipFS := NewZipFSWithOptions(zr, Options{MaxFileSize: 1000})
+		if _, err := zipFS.ReadFile("small.txt"); err != nil {
+			t.Error(err)
+		}
+		if _, err := zipFS.ReadFile("big.txt"); !errors.Is(err, ErrSizeLimitExceeded) {
+			t.Errorf("ReadFile: got %v, want ErrSizeLimitExceeded", err)
+		}
+		f, err := zipFS.Open("big.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.Read(make([]byte, 10)); !errors.Is(err, ErrSizeLimitExceeded) {
+			t.Errorf("Read: got %v, want ErrSizeLimitExceeded", err)
+		}
+		// Metadata is still available
+		if fi, err := f.Stat(); err != nil || fi.Size() != 1<<20 {
+			t.Errorf("Stat: got %v, %v", fi, err)
+		}
+	})
+
+	t.Run("MaxTotalSize", func(t *testing.T) {
+		zipFS := NewZipFSWithOptions(zr, Options{MaxTotalSize: 250})
+		for _, name := range []string{"small.txt", "stored.txt"} {
+			if _, err := zipFS.ReadFile(name); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+		// 200 bytes read, 50 remaining
+		if _, err := zipFS.ReadFile("small.txt"); !errors.Is(err, ErrSizeLimitExceeded) {
+			t.Errorf("got %v, want ErrSizeLimitExceeded", err)
+		}
+		f, err := zipFS.Open("stored.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.(io.ReaderAt).ReadAt(make([]byte, 10), 0); !errors.Is(err, ErrSizeLimitExceeded) {
+			t.Errorf("ReadAt: got %v, want ErrSizeLimitExceeded", err)
+		}
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		zipFS := NewZipFS(zr)
+		b, err := zipFS.ReadFile("big.txt")
+		if err != nil || len(b) != 1<<20 {
+			t.Errorf("got %d bytes, %v", len(b), err)
+		}
+	})
+}
+
+func TestDirModTime(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, e := range []struct {
+		name    string
+		modTime time.Time
+	}{
+		{"a/b/old.txt", day(2)},
+		{"a/b/new.txt", day(5)},
+		{"a/file.txt", day(3)},
+		{"explicit/", day(1)},
+		{"explicit/newest.txt", day(9)},
+		{"top.txt", day(4)},
+	} {
+		hdr := &zip.FileHeader{Name: e.name, Modified: e.modTime}
+		if _, err := w.CreateHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 2 {
+		zipFS := NewZipFS(zr)
+		for dir, want := range map[string]time.Time{
+			"a/b":      day(5),
+			"a":        day(5),
+			"explicit": day(1), // Explicit entry of the zip
+			".":        day(9),
+		} {
+			fi, err := fs.Stat(zipFS, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fi.ModTime().Equal(want) {
+				t.Errorf("#%d %s: got %v, want %v", i, dir, fi.ModTime(), want)
+			}
+		}
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipFS := NewZipFS(zr)
+
+	walk := func(walkDir func(string, fs.WalkDirFunc) error, root string, skip string) (visited []string, err error) {
+		err = walkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				visited = append(visited, "error:"+p)
+				return err
+			}
+			visited = append(visited, p+":"+d.Type().String())
+			if p == skip {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		return
+	}
+
+	for _, tc := range []struct{ root, skip string }{
+		{".", ""},
+		{"dir", ""},
+		{"dir/subdir", ""},
+		{"hello.txt", ""},
+		{".", "dir"},
+		{".", "dir/subdir/a.txt"},
+		{"missing", ""},
+		{"/invalid", ""},
+	} {
+		want, wantErr := walk(func(root string, fn fs.WalkDirFunc) error {
+			return fs.WalkDir(zipFS, root, fn)
+		}, tc.root, tc.skip)
+		got, err := walk(zipFS.WalkDir, tc.root, tc.skip)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s (skip %q):\ngot  %q\nwant %q", tc.root, tc.skip, got, want)
+		}
+		if (err == nil) != (wantErr == nil) {
+			t.Errorf("%s: got error %v, want %v", tc.root, err, wantErr)
+		}
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, _ := w.Create("dir/hello.txt")
+	f.Write([]byte("Hello"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	name := filepath.Join(dir, "test.zip")
+	if err := os.WriteFile(name, buf.Bytes(), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	zipFS, err := OpenFile(name, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(zipFS, "dir/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Hello" {
+		t.Errorf("got %q", b)
+	}
+	if err := zipFS.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The index is still available, but not the content
+	if _, err := fs.Stat(zipFS, "dir/hello.txt"); err != nil {
+		t.Error(err)
+	}
+	if _, err := fs.ReadFile(zipFS, "dir/hello.txt"); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("ReadFile after Close: got %v, want os.ErrClosed", err)
+	}
+
+	if _, err := OpenFile(filepath.Join(dir, "missing.zip"), Options{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v, want fs.ErrNotExist", err)
+	}
+	notZip := filepath.Join(dir, "not.zip")
+	if err := os.WriteFile(notZip, []byte("not a zip archive"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFile(notZip, Options{}); !errors.Is(err, zip.ErrFormat) {
+		t.Errorf("invalid archive: got %v, want zip.ErrFormat", err)
+	}
+
+	// No-op for a ZipFS built from a zip.Reader
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewZipFS(zr).Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTotals(t *testing.T) {
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipFS := NewZipFSWithOptions(zr, Options{LazyIndex: true})
+
+	if n := zipFS.NumFiles(); n != 5 {
+		t.Errorf("NumFiles() = %d, want 5", n)
+	}
+	// dir, dir/subdir, empty, other
+	if n := zipFS.NumDirs(); n != 4 {
+		t.Errorf("NumDirs() = %d, want 4", n)
+	}
+
+	var size, compressed int64
+	err = fs.WalkDir(zipFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		compressed += int64(fi.Sys().(*zip.FileHeader).CompressedSize64)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := zipFS.TotalUncompressedSize(); got != size || size != 68 {
+		t.Errorf("TotalUncompressedSize() = %d, want %d", got, size)
+	}
+	if got := zipFS.TotalCompressedSize(); got != compressed {
+		t.Errorf("TotalCompressedSize() = %d, want %d", got, compressed)
+	}
+}
+
+func TestDirReaderSorted(t *testing.T) {
+	zr, err := createTestZip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipFS := NewZipFS(zr)
+
+	names := func(entries []fs.DirEntry) []string {
+		var s []string
+		for _, e := range entries {
+			s = append(s, e.Name())
+		}
+		return s
+	}
+	want := []string{"dir", "empty", "hello.txt", "other"}
+
+	f, err := zipFS.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dir := f.(fs.ReadDirFile)
+	first, err := dir.ReadDir(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Appending must not overwrite the shared entries
+	_ = append(first, first[0])
+	rest, err := dir.ReadDir(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(append(first, rest...)); !slices.Equal(got, want) {
+		t.Errorf("File.ReadDir: got %q, want %q", got, want)
+	}
+
+	// Modifying the result of ReadDir(-1) must not modify the index
+	slices.Reverse(rest)
+	entries, err := zipFS.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(entries); !slices.Equal(got, want) {
+		t.Errorf("ReadDir: got %q, want %q", got, want)
+	}
+}
+
+func BenchmarkWalkDir(b *testing.B) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for i := range 20000 {
+		if _, err := w.Create(fmt.Sprintf("d%d/d%d/f%d.txt", i%10, i%100, i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	nop := func(string, fs.DirEntry, error) error { return nil }
+
+	b.Run("fs.WalkDir", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			fs.WalkDir(zipFS, ".", nop)
+		}
+	})
+	b.Run("ZipFS.WalkDir", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			zipFS.WalkDir(".", nop)
+		}
+	})
+}
+
+func TestDecodeCP437(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, hdr := range []*zip.FileHeader{
+		{Name: "caf\x82/r\x93le.txt", NonUTF8: true}, // CP437
+		{Name: "naïve.txt"},                          // UTF-8
+	} {
+		f, err := w.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(hdr.Name))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Default: raw names, which are not valid paths
+	if _, err := NewZipFS(zr).Stat("café/rôle.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want fs.ErrNotExist", err)
+	}
+
+	zipFS := NewZipFSWithOptions(zr, Options{DecodeCP437: true})
+	b, err := zipFS.ReadFile("café/rôle.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "caf\x82/r\x93le.txt" {
+		t.Errorf("content: got %q", b)
+	}
+	fi, err := zipFS.Stat("café/rôle.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "rôle.txt" {
+		t.Errorf("Name: got %q", fi.Name())
+	}
+	// The zip.Reader of the caller is not modified
+	if zr.File[0].Name != "caf\x82/r\x93le.txt" {
+		t.Errorf("zip.Reader modified: %q", zr.File[0].Name)
+	}
+
+	if err := fstest.TestFS(zipFS, "café/rôle.txt", "naïve.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+// createRawEncrypted adds an entry encrypted with ZipCrypto to w.
+func createRawEncrypted(t *testing.T, w *zip.Writer, name string, method uint16, content, password string) {
+	t.Helper()
+	var data bytes.Buffer
+	if method == zip.Deflate {
+		fw, _ := flate.NewWriter(&data, flate.BestCompression)
+		fw.Write([]byte(content))
+		fw.Close()
+	} else {
+		data.WriteString(content)
+	}
+	crc := crc32.ChecksumIEEE([]byte(content))
+
+	header := make([]byte, zipCryptoHeaderSize)
+	header[zipCryptoHeaderSize-1] = byte(crc >> 24)
+	plain := append(header, data.Bytes()...)
+
+	keys := newZipCryptoKeys(password)
+	encrypted := make([]byte, len(plain))
+	for i, p := range plain {
+		encrypted[i] = p ^ keys.streamByte()
+		keys.update(p)
+	}
+
+	fw, err := w.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             method,
+		Flags:              flagEncrypted,
+		CRC32:              crc,
+		CompressedSize64:   uint64(len(encrypted)),
+		UncompressedSize64: uint64(len(content)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(encrypted)
+}
+
+type testDecrypter struct{}
+
+func (testDecrypter) Open(f *zip.File, password string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("decrypted with " + password)), nil
+}
+
+func TestEncrypted(t *testing.T) {
+	const password = "secret"
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	createRawEncrypted(t, w, "store.txt", zip.Store, "stored content", password)
+	createRawEncrypted(t, w, "deflate.txt", zip.Deflate, strings.Repeat("deflated content ", 100), password)
+	if _, err := w.CreateRaw(&zip.FileHeader{Name: "aes.txt", Method: methodAES, Flags: flagEncrypted}); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := w.Create("plain.txt"); err != nil {
+		t.Fatal(err)
+	} else {
+		f.Write([]byte("plain"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("no password", func(t *testing.T) {
+		zipFS := NewZipFS(zr)
+		for _, name := range []string{"store.txt", "deflate.txt", "aes.txt"} {
+			if _, err := zipFS.ReadFile(name); !errors.Is(err, ErrEncrypted) {
+				t.Errorf("%s: got %v, want ErrEncrypted", name, err)
+			}
+		}
+		if b, err := zipFS.ReadFile("plain.txt"); err != nil || string(b) != "plain" {
+			t.Errorf("plain.txt: got %q, %v", b, err)
+		}
+	})
+
+	t.Run("password", func(t *testing.T) {
+		zipFS := NewZipFSWithOptions(zr, Options{Password: password})
+		if b, err := zipFS.ReadFile("store.txt"); err != nil || string(b) != "stored content" {
+			t.Errorf("store.txt: got %q, %v", b, err)
+		}
+		if b, err := zipFS.ReadFile("deflate.txt"); err != nil || string(b) != strings.Repeat("deflated content ", 100) {
+			t.Errorf("deflate.txt: got %q, %v", b, err)
+		}
+		// AES requires a Decrypter
+		if _, err := zipFS.ReadFile("aes.txt"); !errors.Is(err, ErrEncrypted) {
+			t.Errorf("aes.txt: got %v, want ErrEncrypted", err)
+		}
+
+		// ReadAt and Seek go through decryption
+		f, err := zipFS.Open("store.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		b := make([]byte, 7)
+		if _, err := f.(io.ReaderAt).ReadAt(b, 7); err != nil || string(b) != "content" {
+			t.Errorf("ReadAt: got %q, %v", b, err)
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		zipFS := NewZipFSWithOptions(zr, Options{Password: "wrong"})
+		_, err := zipFS.ReadFile("store.txt")
+		if !errors.Is(err, ErrPassword) && !errors.Is(err, zip.ErrChecksum) {
+			t.Errorf("got %v, want ErrPassword", err)
+		}
+	})
+
+	t.Run("Decrypter", func(t *testing.T) {
+		zipFS := NewZipFSWithOptions(zr, Options{Password: password, Decrypter: testDecrypter{}})
+		if b, err := zipFS.ReadFile("aes.txt"); err != nil || string(b) != "decrypted with secret" {
+			t.Errorf("aes.txt: got %q, %v", b, err)
+		}
+	})
+}
+
+func BenchmarkReadDir(b *testing.B) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for i := range 2000 {
+		if _, err := w.Create(fmt.Sprintf("dir/f%d.txt", (i*7919)%2000)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("ZipFS.ReadDir", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			zipFS.ReadDir("dir")
+		}
+	})
+	b.Run("File.ReadDir(100)", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			f, _ := zipFS.Open("dir")
+			f.(fs.ReadDirFile).ReadDir(100)
+			f.Close()
+		}
+	})
+	b.Run("File.ReadDir(-1)", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			f, _ := zipFS.Open("dir")
+			f.(fs.ReadDirFile).ReadDir(-1)
+			f.Close()
+		}
+	})
+}
+
+func TestNewFromBytes(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, _ := w.Create("a.txt")
+	f.Write([]byte("A"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{LazyIndex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(zipFS, "a.txt"); err != nil {
+		t.Error(err)
+	}
+
+	// Truncated archive
+	if _, err := NewFromBytes(buf.Bytes()[:buf.Len()-1], Options{}); !errors.Is(err, zip.ErrFormat) {
+		t.Errorf("truncated: got %v, want zip.ErrFormat", err)
+	}
+	if _, err := NewFromBytes(nil, Options{}); !errors.Is(err, zip.ErrFormat) {
+		t.Errorf("nil: got %v, want zip.ErrFormat", err)
+	}
+}
+
+func TestDuplicateEntries(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, e := range []struct{ name, content string }{
+		{"a.txt", "first"},
+		{"dir/b.txt", "B"},
+		{"dir/", ""},
+		{"a.txt", "last"},
+		{"dir/", ""},
+	} {
+		f, err := w.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(e.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := zipFS.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"a.txt", "dir"}; !slices.Equal(names, want) {
+		t.Errorf("ReadDir: got %q, want %q", names, want)
+	}
+
+	// The last one wins
+	b, err := zipFS.ReadFile("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "last" {
+		t.Errorf("a.txt: got %q, want %q", b, "last")
+	}
+	fi, err := entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(len("last")) {
+		t.Errorf("a.txt: Size() = %d from ReadDir", fi.Size())
+	}
+
+	// The explicit directory entry keeps the content seen before it
+	if _, err := zipFS.Stat("dir/b.txt"); err != nil {
+		t.Error(err)
+	}
+	if n := zipFS.NumFiles(); n != 2 {
+		t.Errorf("NumFiles() = %d, want 2", n)
+	}
+	if err := fstest.TestFS(zipFS, "a.txt", "dir/b.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnsafePaths(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	names := []string{"ok.txt", "../evil", "a/../../b", "/abs", `..\win`, "a/../inside.txt", "dir/./x.txt"}
+	for _, name := range names {
+		// CreateRaw doesn't check the name
+		if _, err := w.CreateRaw(&zip.FileHeader{Name: name, Method: zip.Store}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var skipped []string
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{
+		OnSkip: func(f *zip.File, err error) {
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("%s: unexpected reason: %v", f.Name, err)
+			}
+			skipped = append(skipped, f.Name)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"../evil", "a/../../b", "/abs", `..\win`}
+	if !slices.Equal(skipped, want) {
+		t.Errorf("OnSkip: got %q, want %q", skipped, want)
+	}
+	var rejected []string
+	for _, f := range zipFS.Rejected() {
+		rejected = append(rejected, f.Name)
+	}
+	if !slices.Equal(rejected, want) {
+		t.Errorf("Rejected: got %q, want %q", rejected, want)
+	}
+
+	entries, err := zipFS.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root []string
+	for _, e := range entries {
+		root = append(root, e.Name())
+	}
+	if want := []string{"dir", "inside.txt", "ok.txt"}; !slices.Equal(root, want) {
+		t.Errorf("ReadDir: got %q, want %q", root, want)
+	}
+	if err := fstest.TestFS(zipFS, "ok.txt", "inside.txt", "dir/x.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPreserveMode(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, mode := range map[string]fs.FileMode{
+		"bin/":       fs.ModeDir | 0o750,
+		"bin/run.sh": 0o755,
+		"data.txt":   0o644,
+		"lib/a.txt":  0o600, // lib has no entry
+	} {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Store}
+		hdr.SetMode(mode)
+		if _, err := w.CreateHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		preserve bool
+		want     map[string]fs.FileMode
+	}{
+		{false, map[string]fs.FileMode{
+			"bin":        fs.ModeDir | 0o555,
+			"bin/run.sh": 0o555,
+			"data.txt":   0o444,
+			"lib":        fs.ModeDir | 0o555,
+			"lib/a.txt":  0o400,
+		}},
+		{true, map[string]fs.FileMode{
+			"bin":        fs.ModeDir | 0o750,
+			"bin/run.sh": 0o755,
+			"data.txt":   0o644,
+			"lib":        fs.ModeDir | 0o555,
+			"lib/a.txt":  0o600,
+		}},
+	} {
+		zipFS, err := NewFromBytes(buf.Bytes(), Options{PreserveMode: tc.preserve})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range tc.want {
+			fi, err := zipFS.Stat(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Mode() != want {
+				t.Errorf("PreserveMode=%t: %s: got %v, want %v", tc.preserve, name, fi.Mode(), want)
+			}
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, name := range []string{"b.txt", "dir/", "dir/z.txt", "dir/sub/a.txt", "a.txt", "b.txt", "../evil"} {
+		if _, err := w.CreateRaw(&zip.FileHeader{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(files iter.Seq2[string, fs.DirEntry]) []string {
+		var names []string
+		for name, entry := range files {
+			if entry.IsDir() || entry.Name() != path.Base(name) {
+				t.Errorf("%s: got entry %v", name, entry)
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+
+	// Archive order, duplicate at its first position, no directory
+	want := []string{"b.txt", "dir/z.txt", "dir/sub/a.txt", "a.txt"}
+	if got := collect(zipFS.Files()); !slices.Equal(got, want) {
+		t.Errorf("Files: got %q, want %q", got, want)
+	}
+	for name, entry := range zipFS.Files() {
+		if name == "b.txt" && entry.(*fileEntry).file != zipFS.reader.File[5] {
+			t.Error("b.txt: the last duplicate must win")
+		}
+		break
+	}
+
+	sub, err := zipFS.Sub("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"z.txt", "sub/a.txt"}
+	if got := collect(sub.(*subFS).Files()); !slices.Equal(got, want) {
+		t.Errorf("Sub(dir).Files: got %q, want %q", got, want)
+	}
+
+	// No allocation per file
+	buf.Reset()
+	w = zip.NewWriter(buf)
+	for i := range 100 {
+		if _, err := w.Create(fmt.Sprintf("d%d/f%d.txt", i%7, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	bigFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterate := func(z *ZipFS) func() {
+		return func() {
+			for range z.Files() {
+			}
+		}
+	}
+	if small, big := testing.AllocsPerRun(10, iterate(zipFS)), testing.AllocsPerRun(10, iterate(bigFS)); big != small {
+		t.Errorf("Files: %v allocations for 4 files, %v for 100 files", small, big)
+	}
+}
+
+func TestOpenDirAsZip(t *testing.T) {
+	modTime := time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC)
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, f := range []struct {
+		name   string
+		method uint16
+		data   string
+	}{
+		{"top.txt", zip.Deflate, "top"},
+		{"dir/", zip.Store, ""},
+		{"dir/a.txt", zip.Deflate, strings.Repeat("deflated ", 100)},
+		{"dir/empty/", zip.Store, ""},
+		{"dir/sub/b.txt", zip.Store, "stored"},
+	} {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method, Modified: modTime})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, f.data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := zipFS.OpenDirAsZip("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(modTime) {
+			t.Errorf("%s: got Modified %v, want %v", f.Name, f.Modified, modTime)
+		}
+	}
+	if want := []string{"empty/", "sub/", "a.txt", "sub/b.txt"}; !slices.Equal(names, want) {
+		t.Fatalf("got %q, want %q", names, want)
+	}
+	if m := zr.File[2].Method; m != zip.Deflate {
+		t.Errorf("a.txt: got method %d, want Deflate", m)
+	}
+	if m := zr.File[3].Method; m != zip.Store {
+		t.Errorf("sub/b.txt: got method %d, want Store", m)
+	}
+	if err := fstest.TestFS(zr, "a.txt", "sub/b.txt", "empty"); err != nil {
+		t.Error(err)
+	}
+	if got, err := fs.ReadFile(zr, "a.txt"); err != nil || string(got) != strings.Repeat("deflated ", 100) {
+		t.Errorf("a.txt: got %q, %v", got, err)
+	}
+
+	// The whole archive
+	rc, err = zipFS.OpenDirAsZip(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr, err = zip.NewReader(bytes.NewReader(b), int64(len(b))); err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 6 {
+		t.Errorf(".: got %d entries, want 6", len(zr.File))
+	}
+
+	// Closing before the end stops the writer
+	rc, err = zipFS.OpenDirAsZip(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]error{
+		"top.txt": nil,
+		"missing": fs.ErrNotExist,
+		"/dir":    fs.ErrInvalid,
+	} {
+		_, err := zipFS.OpenDirAsZip(name)
+		if err == nil || (want != nil && !errors.Is(err, want)) {
+			t.Errorf("%s: got error %v, want %v", name, err, want)
+		}
+	}
+}
+
+func TestFileWriteTo(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, method := range map[string]uint16{"stored.txt": zip.Store, "deflated.txt": zip.Deflate} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []Options{{}, {MaxTotalSize: 1 << 20}} {
+		zipFS, err := NewFromBytes(buf.Bytes(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"stored.txt", "deflated.txt"} {
+			f, err := zipFS.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := f.(io.WriterTo); !ok {
+				t.Fatal("not an io.WriterTo")
+			}
+
+			var out bytes.Buffer
+			if n, err := io.Copy(&out, f); err != nil || n != int64(len(content)) || !bytes.Equal(out.Bytes(), content) {
+				t.Errorf("%s: io.Copy: got %d, %v", name, n, err)
+			}
+
+			// After a seek, from the current offset
+			if _, err := f.(io.Seeker).Seek(9990, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			out.Reset()
+			if n, err := io.Copy(&out, f); err != nil || n != 10 || out.String() != "0123456789" {
+				t.Errorf("%s: io.Copy after Seek: got %d %q, %v", name, n, out.String(), err)
+			}
+			if n, err := f.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+				t.Errorf("%s: Read after io.Copy: got %d, %v", name, n, err)
+			}
+			f.Close()
+		}
+	}
+
+	// MaxTotalSize applies: the second file exceeds it
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{MaxTotalSize: 15000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"stored.txt", "deflated.txt"} {
+		f, err := zipFS.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.(io.Seeker).Seek(0, io.SeekStart)
+		if _, err := io.Copy(io.Discard, f); name == "deflated.txt" && !errors.Is(err, ErrSizeLimitExceeded) {
+			t.Errorf("%s: got %v, want ErrSizeLimitExceeded", name, err)
+		}
+		f.Close()
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<20) // 16 MiB
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, method := range map[string]uint16{"stored": zip.Store, "deflated": zip.Deflate} {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			b.Fatal(err)
+		}
+		f.Write(content)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, name := range []string{"stored", "deflated"} {
+		b.Run(name+"/Read", func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			for b.Loop() {
+				f, _ := zipFS.Open(name)
+				io.Copy(io.Discard, struct{ io.Reader }{f}) // hide WriteTo
+				f.Close()
+			}
+		})
+		b.Run(name+"/WriteTo", func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			for b.Loop() {
+				f, _ := zipFS.Open(name)
+				io.Copy(io.Discard, f)
+				f.Close()
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, f := range []struct {
+		name   string
+		method uint16
+	}{
+		{"good.txt", zip.Deflate},
+		{"dir/stored.txt", zip.Store},
+		{"dir/deflated.txt", zip.Deflate},
+		{"empty.txt", zip.Store},
+	} {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.name != "empty.txt" {
+			io.WriteString(fw, strings.Repeat(f.name, 100))
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zipFS.VerifyAll(); err != nil {
+		t.Errorf("VerifyAll: %v", err)
+	}
+
+	// Alter the stored content
+	corrupted := bytes.Clone(buf.Bytes())
+	offset, err := zipFS.files["dir/stored.txt"].DataOffset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted[offset+500] ^= 0xFF
+	zipFS, err = NewFromBytes(corrupted, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A partial read doesn't detect the corruption
+	f, err := zipFS.Open("dir/stored.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for name, want := range map[string]error{
+		"good.txt":         nil,
+		"empty.txt":        nil,
+		"dir/stored.txt":   zip.ErrChecksum,
+		"dir/deflated.txt": nil,
+		"dir":              errors.New("is a directory"),
+		"missing.txt":      fs.ErrNotExist,
+		"../good.txt":      fs.ErrInvalid,
+	} {
+		err := zipFS.Verify(name)
+		switch {
+		case want == nil && err != nil:
+			t.Errorf("Verify(%q): %v", name, err)
+		case want != nil && err == nil:
+			t.Errorf("Verify(%q): no error, want %v", name, want)
+		case want == zip.ErrChecksum && (!errors.Is(err, want) || !strings.Contains(err.Error(), "want ")):
+			t.Errorf("Verify(%q): got %v, want %v with checksums", name, err, want)
+		}
+	}
+
+	err = zipFS.VerifyAll()
+	var pe *fs.PathError
+	if !errors.Is(err, zip.ErrChecksum) || !errors.As(err, &pe) || pe.Path != "dir/stored.txt" {
+		t.Errorf("VerifyAll: got %v", err)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, content := range map[string]string{
+		"go.mod":     "module example.com/old\n",
+		"main.go":    "package main\n",
+		"sub/a.go":   "package sub\n",
+		"sub/b.go":   "package sub\n",
+		"other/c.go": "package other\n",
+	} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zipFS, err := NewFromBytes(buf.Bytes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ofs := zipFS.Overlay(fstest.MapFS{
+		"go.mod":   &fstest.MapFile{Data: []byte("module example.com/new\n")},
+		"sub/b.go": &fstest.MapFile{Data: []byte("package sub // patched\n")},
+		"sub/z.go": &fstest.MapFile{Data: []byte("package sub // added\n")},
+		"new/e.go": &fstest.MapFile{Data: []byte("package new\n")},
+	})
+
+	if err := fstest.TestFS(ofs,
+		"go.mod", "main.go", "sub/a.go", "sub/b.go", "sub/z.go", "other/c.go", "new/e.go",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"go.mod":   "module example.com/new\n",
+		"main.go":  "package main\n",
+		"sub/b.go": "package sub // patched\n",
+		"sub/a.go": "package sub\n",
+	} {
+		if got, err := fs.ReadFile(ofs, name); err != nil || string(got) != want {
+			t.Errorf("ReadFile(%q): got %q, %v", name, got, err)
+		}
+		f, err := ofs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := io.ReadAll(f); err != nil || string(got) != want {
+			t.Errorf("Open(%q): got %q, %v", name, got, err)
+		}
+		f.Close()
+	}
+
+	var names []string
+	entries, err := fs.ReadDir(ofs, "sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"a.go", "b.go", "z.go"}; !slices.Equal(names, want) {
+		t.Errorf("ReadDir(sub): got %q, want %q", names, want)
+	}
+
+	if _, err := ofs.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(missing): got %v", err)
+	}
+}